@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -120,6 +122,18 @@ func TestBuildAndRun(t *testing.T) {
 		}
 	})
 
+	t.Run("invalid command in warmup", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--shell", "none", "--warmups", "1", "--runs", "2", "--skip-calibration", "nonexistent-command-12345")
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Error("Expected non-zero exit for invalid command")
+		}
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "Error: ") || strings.Contains(outputStr, "benchmark runs") {
+			t.Errorf("Expected the series to stop at the warmup, got: %s", outputStr)
+		}
+	})
+
 	t.Run("multiple runs summary", func(t *testing.T) {
 		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "3", "--skip-calibration", "echo", "test")
 		output, err := cmd.CombinedOutput()
@@ -141,30 +155,423 @@ func TestBuildAndRun(t *testing.T) {
 		}
 	})
 
-	t.Run("zero runs behavior", func(t *testing.T) {
-		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "0", "--skip-calibration", "echo", "test")
+	t.Run("multiple commands comparison", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "2", "--skip-calibration",
+			"--command", "sleep 0.2", "--command", "sleep 0.05")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("Expected successful run, got error: %v, output: %s", err, string(output))
+		}
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "Benchmark 1/2:") || !strings.Contains(outputStr, "Benchmark 2/2:") {
+			t.Errorf("Expected a header per command, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "sleep 0.05 ran") {
+			t.Errorf("Expected fastest command to be ranked first, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "times faster than sleep 0.2") {
+			t.Errorf("Expected relative speedup report, got: %s", outputStr)
+		}
+	})
+
+	t.Run("positional command groups", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "1", "--skip-calibration",
+			"echo", "a", ":::", "echo", "b")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("Expected successful run, got error: %v, output: %s", err, string(output))
+		}
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "Benchmark 2/2: echo b") {
+			t.Errorf("Expected second command group, got: %s", outputStr)
+		}
+	})
+
+	t.Run("parameter scan", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "1", "--skip-calibration",
+			"--parameter-scan", "n", "1", "3", "--command", "echo {n}")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("Expected successful run, got error: %v, output: %s", err, string(output))
+		}
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "Benchmark 3/3: echo 3") {
+			t.Errorf("Expected a benchmark per parameter value, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "Parameter scan: n") {
+			t.Errorf("Expected parameter table, got: %s", outputStr)
+		}
+	})
+
+	t.Run("parameter list", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "1", "--skip-calibration",
+			"--parameter-list", "word", "a,b", "echo", "{word}")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("Expected successful run, got error: %v, output: %s", err, string(output))
+		}
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "Benchmark 2/2: echo b") {
+			t.Errorf("Expected a benchmark per list value, got: %s", outputStr)
+		}
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		logFile := t.TempDir() + "/hooks.log"
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "2", "--warmups", "1", "--skip-calibration",
+			"--setup", "echo setup >> "+logFile,
+			"--prepare", "echo prepare >> "+logFile,
+			"--cleanup", "echo cleanup >> "+logFile,
+			"--conclude", "echo conclude >> "+logFile,
+			"echo", "test")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("Expected successful run, got error: %v, output: %s", err, string(output))
+		}
+
+		log, err := os.ReadFile(logFile)
+		if err != nil {
+			t.Fatalf("Expected hooks to write log: %v", err)
+		}
+		expected := "setup\nprepare\ncleanup\nprepare\ncleanup\nprepare\ncleanup\nconclude\n"
+		if string(log) != expected {
+			t.Errorf("Expected hook order %q, got %q", expected, string(log))
+		}
+	})
+
+	t.Run("failing prepare hook", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "2", "--skip-calibration",
+			"--prepare", "echo cache busy; exit 1", "echo", "test")
 		output, _ := cmd.CombinedOutput()
 		outputStr := string(output)
-		if !strings.Contains(outputStr, "Running 0 benchmark runs") {
-			t.Errorf("Expected to see 0 runs header, got: %s", outputStr)
+		if !strings.Contains(outputStr, "prepare hook failed") {
+			t.Errorf("Expected prepare hook failure, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "prepare: cache busy") {
+			t.Errorf("Expected hook output, got: %s", outputStr)
 		}
-		if !strings.Contains(outputStr, "No successful runs") {
-			t.Errorf("Expected 'No successful runs' message, got: %s", outputStr)
+		if strings.Contains(outputStr, "Run 1:") {
+			t.Errorf("Expected no runs after failed prepare hook, got: %s", outputStr)
 		}
 	})
 
-	t.Run("negative runs causes panic", func(t *testing.T) {
-		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "-1", "--skip-calibration", "echo", "test")
+	t.Run("non-zero exit fails the run", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "2", "--skip-calibration", "sh", "-c", "exit 3")
 		output, err := cmd.CombinedOutput()
 		if err == nil {
-			t.Error("Expected non-zero exit for negative runs")
+			t.Error("Expected non-zero exit when every run fails")
 		}
 		outputStr := string(output)
-		if !strings.Contains(outputStr, "panic") {
-			t.Errorf("Expected panic for negative runs, got: %s", outputStr)
+		if !strings.Contains(outputStr, "exited with code 3") {
+			t.Errorf("Expected exit code in output, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "--ignore-failure") {
+			t.Errorf("Expected hint about --ignore-failure, got: %s", outputStr)
+		}
+	})
+
+	t.Run("ignore failure counts non-zero exits", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "2", "--skip-calibration", "--ignore-failure", "sh", "-c", "exit 3")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Errorf("Expected success with --ignore-failure, got: %v\n%s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "exited with code 3, ignored") {
+			t.Errorf("Expected ignored exit code in output, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "Mean:") {
+			t.Errorf("Expected statistics for ignored failures, got: %s", outputStr)
+		}
+	})
+
+	t.Run("interrupt prints partial summary", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "20", "--skip-calibration", "sleep", "0.2")
+		var output strings.Builder
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		time.Sleep(700 * time.Millisecond)
+		cmd.Process.Signal(os.Interrupt)
+
+		err := cmd.Wait()
+		outputStr := output.String()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 130 {
+			t.Errorf("Expected exit code 130 after interrupt, got %v", err)
+		}
+		if !strings.Contains(outputStr, "Interrupted after") {
+			t.Errorf("Expected interruption notice, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "Mean:") {
+			t.Errorf("Expected partial summary, got: %s", outputStr)
+		}
+	})
+
+	t.Run("shell mode runs pipes", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--shell", "/bin/sh", "--calibration", "2", "--phrase", "3", "--command", "seq 3 | tail -1")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Errorf("Expected the piped command to find its phrase, got: %v\n%s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "Shell overhead:") {
+			t.Errorf("Expected shell calibration, got: %s", outputStr)
+		}
+	})
+
+	t.Run("shell mode keeps positional arguments whole", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "My App")
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		tool := filepath.Join(dir, "tool")
+		if err := os.WriteFile(tool, []byte("#!/bin/sh\necho \"ready $#\"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{tool}, {tool, "a b"}} {
+			cmd := exec.Command("./test-benchmark", append([]string{"--cli", "--shell", "/bin/sh", "--runs", "1", "--skip-calibration", "--phrase", fmt.Sprintf("ready %d", len(args)-1)}, args...)...)
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Errorf("Expected %q to run unchanged through the shell, got: %v\n%s", args, err, output)
+			}
+		}
+	})
+
+	t.Run("direct exec calibration", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--shell", "none", "--calibration", "2", "echo", "test")
+		output, _ := cmd.CombinedOutput()
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "Exec overhead:") {
+			t.Errorf("Expected exec calibration without a shell, got: %s", outputStr)
+		}
+	})
+
+	t.Run("per-run output files", func(t *testing.T) {
+		dir := t.TempDir()
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "2", "--warmups", "1", "--skip-calibration",
+			"--output", dir+"/out-{run}.log", "--command", "echo out; echo err >&2")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Expected successful run, got error: %v, output: %s", err, string(output))
 		}
-		if !strings.Contains(outputStr, "makeslice: cap out of range") {
-			t.Errorf("Expected makeslice error for negative runs, got: %s", outputStr)
+		for _, run := range []string{"warmup-1", "1", "2"} {
+			contents, err := os.ReadFile(dir + "/out-" + run + ".log")
+			if err != nil {
+				t.Fatalf("Expected an output file for run %s: %v", run, err)
+			}
+			if string(contents) != "out\nerr\n" {
+				t.Errorf("Expected stdout and stderr in the output file, got %q", string(contents))
+			}
+		}
+		if !strings.Contains(string(output), "Output: written to "+dir+"/out-{run}.log") {
+			t.Errorf("Expected the output mode in the summary, got: %s", string(output))
+		}
+	})
+
+	t.Run("inherited stdin", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "1", "--skip-calibration", "--stdin", "inherit",
+			"--phrase", "got from chrono", "--command", "read line; echo got $line")
+		cmd.Stdin = strings.NewReader("from chrono\n")
+		output, err := cmd.CombinedOutput()
+		if err != nil || !strings.Contains(string(output), "Time: ") {
+			t.Errorf("Expected the command to read chrono's stdin, got: %s (%v)", output, err)
+		}
+
+		cmd = exec.Command("./test-benchmark", "--stdin", "inherit", "true")
+		output, err = cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "needs --cli") {
+			t.Errorf("Expected --stdin inherit to need --cli, got: %s", output)
+		}
+	})
+
+	t.Run("inherited output", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "1", "--skip-calibration", "--output", "inherit", "echo", "inherited-line")
+		output, _ := cmd.CombinedOutput()
+		if !strings.Contains(string(output), "inherited-line") {
+			t.Errorf("Expected the command's output on the terminal, got: %s", string(output))
+		}
+	})
+
+	t.Run("null output with phrase", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--output", "null", "--phrase", "x", "echo", "x")
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "--output null") {
+			t.Errorf("Expected --output null to be rejected with a phrase, got: %s", string(output))
+		}
+	})
+
+	t.Run("phrase stream", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--phrase", "ready",
+			"--phrase-stream", "stdout", "--command", "echo 'waiting for ready' >&2; sleep 0.05; echo ready")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected the phrase on stdout, got error: %v, output: %s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "on stdout") || strings.Contains(outputStr, "on stderr") {
+			t.Errorf("Expected the match on stdout, got: %s", outputStr)
+		}
+	})
+
+	t.Run("readiness probe", func(t *testing.T) {
+		ready := filepath.Join(t.TempDir(), "ready")
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--wait-file", ready,
+			"--command", "sleep 0.1; touch "+ready+"; sleep 5")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected the probe to succeed, got error: %v, output: %s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "Probe: file "+ready) || strings.Contains(outputStr, "Time: 5") {
+			t.Errorf("Expected the run to end when the file appeared, got: %s", outputStr)
+		}
+
+		cmd = exec.Command("./test-benchmark", "--cli", "--wait-tcp", "localhost:1", "--phrase", "x", "echo", "x")
+		output, err = cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "cannot combine --wait-*") {
+			t.Errorf("Expected a probe and a phrase to be rejected, got: %s", string(output))
+		}
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--idle-timeout", "200ms",
+			"--command", "echo compiling; sleep 5")
+		output, _ := cmd.CombinedOutput()
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "stalled: no output after") {
+			t.Errorf("Expected the run to be marked stalled, got: %s", outputStr)
+		}
+	})
+
+	t.Run("until quiet", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--until-quiet", "200ms",
+			"--command", "echo loading; sleep 0.05; echo ready; sleep 5")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected the run to end when the output went quiet, got error: %v, output: %s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "Mode: Until output is quiet for 200ms") {
+			t.Errorf("Expected the quiet mode in the summary, got: %s", outputStr)
+		}
+	})
+
+	t.Run("fail phrase", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--runs", "2",
+			"--fail-phrase", "panic:", "--fail-phrase-regex", "address (already )?in use", "--phrase", "ready",
+			"--command", "echo 'panic: boom' >&2; sleep 5")
+		output, _ := cmd.CombinedOutput()
+		outputStr := string(output)
+		if !strings.Contains(outputStr, `failure phrase "panic:" found on stderr`) {
+			t.Errorf("Expected the runs to fail on the failure phrase, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, `Fail phrases: "panic:", /address (already )?in use/`) {
+			t.Errorf("Expected the failure phrases in the summary, got: %s", outputStr)
+		}
+	})
+
+	t.Run("start phrase", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--start-phrase", "started", "--phrase", "finished",
+			"--command", "sleep 0.1; echo started; sleep 0.05; echo finished")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected the interval to be timed, got error: %v, output: %s", err, outputStr)
+		}
+		if strings.Contains(outputStr, "overhead") || !strings.Contains(outputStr, `Phrase: "started" → "finished"`) {
+			t.Errorf("Expected the interval without calibration, got: %s", outputStr)
+		}
+
+		cmd = exec.Command("./test-benchmark", "--cli", "--start-phrase", "started", "echo", "x")
+		output, err = cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "--start-phrase needs --phrase") {
+			t.Errorf("Expected --start-phrase without --phrase to be rejected, got: %s", string(output))
+		}
+	})
+
+	t.Run("first output", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--runs", "2",
+			"--command", "echo starting; echo warning >&2")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected the runs to succeed, got error: %v, output: %s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "First output: stdout") || !strings.Contains(outputStr, "stderr") {
+			t.Errorf("Expected first output statistics for both streams, got: %s", outputStr)
+		}
+	})
+
+	t.Run("environment and working directory", func(t *testing.T) {
+		dir := t.TempDir()
+		cmd := exec.Command("./test-benchmark", "--cli", "--runs", "1", "--calibration", "1",
+			"--env", "CHRONO_MODE=fast", "--cwd", dir, "--phrase", "fast",
+			"--command", "test -d . && echo $CHRONO_MODE")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected the phrase from the environment, got error: %v, output: %s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "Environment: CHRONO_MODE=fast") || !strings.Contains(outputStr, "Directory: "+dir) {
+			t.Errorf("Expected the environment deltas in the summary, got: %s", outputStr)
+		}
+	})
+
+	t.Run("adaptive runs", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--runs", "auto",
+			"--min-runs", "2", "--max-runs", "4", "--target-rse", "0.0001%", "sleep", "0.01")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected successful run, got error: %v, output: %s", err, outputStr)
+		}
+		if !strings.Contains(outputStr, "Stopped after 4 runs: --max-runs reached") {
+			t.Errorf("Expected the series to stop at --max-runs, got: %s", outputStr)
+		}
+		if !strings.Contains(outputStr, "Precision:") {
+			t.Errorf("Expected the precision in the summary, got: %s", outputStr)
+		}
+	})
+
+	t.Run("adaptive flags need auto runs", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--max-runs", "4", "true")
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "need --runs auto") {
+			t.Errorf("Expected --max-runs without --runs auto to be rejected, got: %s", string(output))
+		}
+	})
+
+	t.Run("concurrent load", func(t *testing.T) {
+		cmd := exec.Command("./test-benchmark", "--cli", "--skip-calibration", "--runs", "6", "--concurrency", "3", "sleep", "0.1")
+		output, err := cmd.CombinedOutput()
+		outputStr := string(output)
+		if err != nil {
+			t.Fatalf("Expected successful run, got error: %v, output: %s", err, outputStr)
+		}
+		for _, expected := range []string{"6 benchmark runs, 3 at a time", "Throughput:", "Latency: p50"} {
+			if !strings.Contains(outputStr, expected) {
+				t.Errorf("Expected %q in the output, got: %s", expected, outputStr)
+			}
+		}
+	})
+
+	t.Run("zero or negative runs", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--runs", "0"},
+			{"--runs", "-1"},
+			{"--runs", "-2", "--concurrency", "2"},
+		} {
+			args = append([]string{"--cli", "--skip-calibration"}, append(args, "echo", "test")...)
+			output, err := exec.Command("./test-benchmark", args...).CombinedOutput()
+			if err == nil {
+				t.Errorf("Expected non-zero exit for %v", args)
+			}
+			outputStr := string(output)
+			if !strings.Contains(outputStr, "--runs must be at least 1") || strings.Contains(outputStr, "panic") {
+				t.Errorf("Expected %v to be rejected, got: %s", args, outputStr)
+			}
 		}
 	})
 }
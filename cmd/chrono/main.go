@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"math"
+	"net"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -17,10 +26,10 @@ import (
 var version = "dev"
 
 func main() {
-	config := parseFlags()
+	configs := parseFlags()
 
-	if !config.UseCli {
-		if err := tui.Run(config); err != nil {
+	if !configs[0].UseCli {
+		if err := tui.Run(configs); err != nil {
 			fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 			os.Exit(1)
 		}
@@ -28,33 +37,166 @@ func main() {
 	}
 
 	var shellOverhead time.Duration
-	if !config.SkipCalibration {
-		output.PrintCalibration(config.CalibrationRuns)
+	if !configs[0].SkipCalibration {
+		output.PrintCalibration(configs[0])
 
-		shellOverhead = shellcalibration.CalibrateShellOverhead(config.CalibrationRuns)
+		shellOverhead = shellcalibration.CalibrateShellOverhead(configs[0])
 
-		output.PrintShellOverhead(shellOverhead)
+		output.PrintShellOverhead(configs[0], shellOverhead)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		// A second interrupt terminates chrono immediately.
+		<-ctx.Done()
+		stop()
+	}()
+
+	allResults := make([][]benchmark.Result, 0, len(configs))
+	for i, config := range configs {
+		if len(configs) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			output.PrintCommandHeader(i+1, len(configs), config)
+		}
+
+		results := runBenchmark(ctx, config, shellOverhead)
+		allResults = append(allResults, results)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	completed := configs[:len(allResults)]
+	if configs[0].ParameterName != "" {
+		output.PrintParameterTable(completed, allResults)
+	} else if len(completed) > 1 {
+		output.PrintComparison(completed, allResults)
+	}
+
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
+
+	for _, results := range allResults {
+		if !slices.ContainsFunc(results, func(r benchmark.Result) bool { return r.Found }) {
+			os.Exit(1)
+		}
+	}
+}
+
+func runBenchmark(ctx context.Context, config benchmark.Config, shellOverhead time.Duration) []benchmark.Result {
+	if _, err := benchmark.RunHook(config, benchmark.HookSetup); err != nil {
+		output.PrintHookFailure(err)
+		return nil
+	}
+
+	results, err := runSeries(ctx, config, shellOverhead)
+	if ctx.Err() != nil {
+		output.PrintInterrupted(len(results), config.Runs)
+	} else if err != nil {
+		output.PrintHookFailure(err)
+	}
+
+	if _, concludeErr := benchmark.RunHook(config, benchmark.HookConclude); concludeErr != nil {
+		output.PrintHookFailure(concludeErr)
+	}
+
+	if err == nil || len(results) > 0 {
+		output.PrintSummary(results, config, shellOverhead)
+	}
+	return results
+}
+
+// runSeries runs the warmups and benchmark runs of a single command. It stops
+// early when a hook fails, the command cannot be run or ctx is cancelled, and
+// returns the results collected so far. Cancelled runs are not included.
+func runSeries(ctx context.Context, config benchmark.Config, shellOverhead time.Duration) ([]benchmark.Result, error) {
 	if config.Warmups > 0 {
 		output.PrintWarmupHeader(config.Warmups)
 		for i := range config.Warmups {
-			result := benchmark.Run(config, shellOverhead)
-			output.PrintWarmupResult(i+1, result)
+			if _, err := benchmark.RunHook(config, benchmark.HookPrepare); err != nil {
+				return nil, err
+			}
+			config.RunLabel = benchmark.RunLabel(true, i+1)
+			result, runErr := benchmark.RunContext(ctx, config, shellOverhead)
+			if ctx.Err() == nil {
+				output.PrintWarmupResult(i+1, result)
+			}
+			if _, err := benchmark.RunHook(config, benchmark.HookCleanup); err != nil {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if runErr != nil {
+				return nil, runErr
+			}
 		}
 		fmt.Println()
 	}
 
-	output.PrintBenchmarkHeader(config.Runs)
+	if config.Concurrency > 1 {
+		return runLoad(ctx, config, shellOverhead)
+	}
+
+	if config.AutoRuns {
+		output.PrintAdaptiveHeader(config)
+	} else {
+		output.PrintBenchmarkHeader(config.Runs)
+	}
 	results := make([]benchmark.Result, 0, config.Runs)
 
-	for i := range config.Runs {
-		result := benchmark.Run(config, shellOverhead)
-		results = append(results, result)
-		output.PrintBenchmarkResult(i+1, result)
+	start := time.Now()
+	for i := 0; ; i++ {
+		reason := config.StopReason(results, time.Since(start))
+		if reason != benchmark.StopNone {
+			if config.AutoRuns {
+				output.PrintStopReason(reason, results)
+			}
+			break
+		}
+
+		if _, err := benchmark.RunHook(config, benchmark.HookPrepare); err != nil {
+			return results, err
+		}
+		config.RunLabel = benchmark.RunLabel(false, i+1)
+		result, runErr := benchmark.RunContext(ctx, config, shellOverhead)
+		if ctx.Err() == nil {
+			results = append(results, result)
+			output.PrintBenchmarkResult(i+1, result)
+		}
+		if _, err := benchmark.RunHook(config, benchmark.HookCleanup); err != nil {
+			return results, err
+		}
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		if runErr != nil {
+			break
+		}
 	}
 
-	output.PrintSummary(results, config, shellOverhead)
+	return results, nil
+}
+
+// runLoad runs the benchmark runs of a command concurrently, printing each
+// result as it finishes.
+func runLoad(ctx context.Context, config benchmark.Config, shellOverhead time.Duration) ([]benchmark.Result, error) {
+	output.PrintLoadHeader(config)
+	load, err := benchmark.RunLoad(ctx, config, shellOverhead, output.PrintBenchmarkResult)
+	if ctx.Err() == nil {
+		output.PrintThroughput(load, config)
+	}
+	// As in a sequential series, a command that cannot be run ends the load
+	// without being reported as a hook failure.
+	var hookErr *benchmark.HookError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &hookErr) {
+		err = nil
+	}
+	return load.Results, err
 }
 
 func parseCommandString(cmd string) ([]string, error) {
@@ -106,61 +248,576 @@ func parseCommandString(cmd string) ([]string, error) {
 	return args, nil
 }
 
-func parseFlags() benchmark.Config {
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseStdin works out where the command's stdin comes from. --input and
+// --input-string are mutually exclusive, and --stdin only applies when neither
+// is given.
+func parseStdin(input, inputString, stdin string) (benchmark.StdinMode, error) {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if stdin != "null" && stdin != "inherit" {
+		return 0, fmt.Errorf("unsupported --stdin mode %q (expected \"null\" or \"inherit\")", stdin)
+	}
+
+	switch {
+	case set["input"] && set["input-string"]:
+		return 0, fmt.Errorf("cannot specify both --input and --input-string")
+	case (set["input"] || set["input-string"]) && set["stdin"]:
+		return 0, fmt.Errorf("cannot combine --stdin with --input or --input-string")
+	case set["input"]:
+		info, err := os.Stat(input)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --input: %w", err)
+		}
+		if info.IsDir() {
+			return 0, fmt.Errorf("invalid --input: %s is a directory", input)
+		}
+		return benchmark.StdinFile, nil
+	case set["input-string"]:
+		return benchmark.StdinString, nil
+	case stdin == "inherit":
+		return benchmark.StdinInherit, nil
+	default:
+		return benchmark.StdinNull, nil
+	}
+}
+
+// parseProbe parses the --wait-* flags, of which at most one may be given.
+func parseProbe(tcp, httpURL, file, unixSocket string, status int, interval time.Duration) (benchmark.Probe, error) {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	probe := benchmark.Probe{Status: status, Interval: interval}
+	count := 0
+	for _, candidate := range []struct {
+		kind   benchmark.ProbeKind
+		target string
+	}{
+		{benchmark.ProbeTCP, tcp},
+		{benchmark.ProbeHTTP, httpURL},
+		{benchmark.ProbeFile, file},
+		{benchmark.ProbeUnixSocket, unixSocket},
+	} {
+		if candidate.target != "" {
+			probe.Kind, probe.Target = candidate.kind, candidate.target
+			count++
+		}
+	}
+
+	switch {
+	case count > 1:
+		return benchmark.Probe{}, fmt.Errorf("only one of --wait-tcp, --wait-http, --wait-file and --wait-unix-socket can be given")
+	case set["wait-status"] && probe.Kind != benchmark.ProbeHTTP:
+		return benchmark.Probe{}, fmt.Errorf("--wait-status needs --wait-http")
+	case set["wait-interval"] && probe.Kind == benchmark.ProbeNone:
+		return benchmark.Probe{}, fmt.Errorf("--wait-interval needs a --wait-* probe")
+	case interval <= 0:
+		return benchmark.Probe{}, fmt.Errorf("--wait-interval must be positive")
+	case status < 100 || status > 599:
+		return benchmark.Probe{}, fmt.Errorf("invalid --wait-status: %d is not an HTTP status code", status)
+	}
+
+	switch probe.Kind {
+	case benchmark.ProbeTCP:
+		if _, _, err := net.SplitHostPort(tcp); err != nil {
+			return benchmark.Probe{}, fmt.Errorf("invalid --wait-tcp: %v", err)
+		}
+	case benchmark.ProbeHTTP:
+		target, err := url.Parse(httpURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return benchmark.Probe{}, fmt.Errorf("invalid --wait-http: expected an http:// or https:// URL, got %q", httpURL)
+		}
+	}
+	return probe, nil
+}
+
+// parseRuns parses --runs, which is a number or "auto", along with the flags
+// that only apply to --runs auto. With auto, Runs is the most runs there can
+// be.
+func parseRuns(runs string, minRuns, maxRuns int, targetRSE string, maxTime time.Duration) (benchmark.Config, error) {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if runs != "auto" {
+		if set["min-runs"] || set["max-runs"] || set["target-rse"] || set["max-time"] {
+			return benchmark.Config{}, fmt.Errorf("--min-runs, --max-runs, --target-rse and --max-time need --runs auto")
+		}
+		n, err := strconv.Atoi(runs)
+		if err != nil {
+			return benchmark.Config{}, fmt.Errorf("invalid --runs: expected a number or \"auto\", got %q", runs)
+		}
+		if n < 1 {
+			return benchmark.Config{}, fmt.Errorf("--runs must be at least 1")
+		}
+		return benchmark.Config{Runs: n}, nil
+	}
+
+	rse, err := strconv.ParseFloat(strings.TrimSuffix(targetRSE, "%"), 64)
+	switch {
+	case err != nil || rse <= 0:
+		return benchmark.Config{}, fmt.Errorf("invalid --target-rse: expected a positive percentage, got %q", targetRSE)
+	case minRuns < 2:
+		return benchmark.Config{}, fmt.Errorf("--min-runs must be at least 2")
+	case maxRuns < minRuns:
+		return benchmark.Config{}, fmt.Errorf("--max-runs must be at least --min-runs")
+	case maxTime < 0:
+		return benchmark.Config{}, fmt.Errorf("--max-time must not be negative")
+	}
+
+	return benchmark.Config{
+		Runs:      maxRuns,
+		AutoRuns:  true,
+		MinRuns:   minRuns,
+		MaxRuns:   maxRuns,
+		TargetRSE: rse / 100,
+		MaxTime:   maxTime,
+	}, nil
+}
+
+func parsePTYSize(value string) (int, int, error) {
+	columns, rows, ok := strings.Cut(value, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected COLUMNSxROWS, got %q", value)
+	}
+	c, err := strconv.Atoi(columns)
+	if err != nil || c < 1 || c > math.MaxUint16 {
+		return 0, 0, fmt.Errorf("invalid number of columns %q", columns)
+	}
+	r, err := strconv.Atoi(rows)
+	if err != nil || r < 1 || r > math.MaxUint16 {
+		return 0, 0, fmt.Errorf("invalid number of rows %q", rows)
+	}
+	return c, r, nil
+}
+
+func parseCheckpoints(values []string) ([]benchmark.Checkpoint, error) {
+	checkpoints := make([]benchmark.Checkpoint, 0, len(values))
+	names := make(map[string]bool, len(values))
+	for _, value := range values {
+		name, phrase, ok := strings.Cut(value, "=")
+		if !ok || name == "" || phrase == "" {
+			return nil, fmt.Errorf("expected NAME=PHRASE, got %q", value)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate checkpoint name %q", name)
+		}
+		names[name] = true
+		checkpoints = append(checkpoints, benchmark.Checkpoint{Name: name, Phrase: phrase})
+	}
+	return checkpoints, nil
+}
+
+const commandSeparator = ":::"
+
+func splitCommandGroups(args []string) ([][]string, error) {
+	var groups [][]string
+	start := 0
+	for i, arg := range args {
+		if arg != commandSeparator {
+			continue
+		}
+		if i == start {
+			return nil, fmt.Errorf("empty command before %q", commandSeparator)
+		}
+		groups = append(groups, args[start:i])
+		start = i + 1
+	}
+	if start == len(args) {
+		return nil, fmt.Errorf("empty command after %q", commandSeparator)
+	}
+	return append(groups, args[start:]), nil
+}
+
+func parseFlags() []benchmark.Config {
+	var commandStrs stringList
+	var checkpointStrs stringList
+	var failPhraseStrs stringList
+	var failRegexStrs stringList
+	var envStrs stringList
+	var parameterScan parameterScanFlag
+	var parameterList parameterListFlag
 	var (
 		versionFlag     = flag.Bool("version", false, "Print version and exit")
 		phrase          = flag.String("phrase", "", "Phrase to search for in command output (if not specified, measures until command completion)")
+		phraseRegex     = flag.String("phrase-regex", "", "Regular expression to search for in command output, alternative to --phrase")
+		startPhrase     = flag.String("start-phrase", "", "Start timing when this phrase appears in output instead of when the command starts (needs --phrase; no shell overhead is subtracted)")
+		phraseStream    = flag.String("phrase-stream", "both", "Output stream searched for the phrase: \"stdout\", \"stderr\" or \"both\"")
+		waitTCP         = flag.String("wait-tcp", "", "End each run once a TCP connection to HOST:PORT succeeds, alternative to --phrase")
+		waitHTTP        = flag.String("wait-http", "", "End each run once a GET request to URL returns --wait-status, alternative to --phrase")
+		waitStatus      = flag.Int("wait-status", 200, "HTTP status code --wait-http waits for")
+		waitFile        = flag.String("wait-file", "", "End each run once PATH is created (a file, directory or socket), or changed if it already existed, alternative to --phrase")
+		waitUnixSocket  = flag.String("wait-unix-socket", "", "End each run once a connection to the Unix socket at PATH succeeds, alternative to --phrase")
+		waitInterval    = flag.Duration("wait-interval", 10*time.Millisecond, "How often the --wait-* probe is tried")
+		ignoreCase      = flag.Bool("ignore-case", false, "Match --phrase, --phrase-regex, --checkpoint and --fail-phrase phrases case-insensitively")
 		warmups         = flag.Int("warmups", 0, "Number of warmup runs before benchmarking")
-		runs            = flag.Int("runs", 1, "Number of benchmark runs")
+		runs            = flag.String("runs", "1", "Number of benchmark runs, or \"auto\" to run until the mean is precise enough")
+		minRuns         = flag.Int("min-runs", 3, "Minimum number of runs with --runs auto")
+		maxRuns         = flag.Int("max-runs", 1000, "Maximum number of runs with --runs auto")
+		targetRSE       = flag.String("target-rse", "1%", "Relative standard error of the mean at which --runs auto stops")
+		maxTime         = flag.Duration("max-time", time.Minute, "Time after which --runs auto stops, once --min-runs are done (0 for no limit)")
 		timeout         = flag.Duration("timeout", 0, "Maximum time to wait for phrase or command completion (default: no timeout)")
-		calibrationRuns = flag.Int("calibration", 5, "Number of calibration runs to measure shell startup overhead")
+		untilQuiet      = flag.Duration("until-quiet", 0, "End each run at the last output before the command writes nothing for this long, alternative to --phrase")
+		idleTimeout     = flag.Duration("idle-timeout", 0, "Stop a run and mark it stalled when the command writes nothing for this long (default: no limit)")
+		calibrationRuns = flag.Int("calibration", 5, "Number of calibration runs to measure shell startup (or exec) overhead")
 		skipCalibration = flag.Bool("skip-calibration", false, "Skip calibration and don't subtract shell overhead")
+		shell           = flag.String("shell", "default", "Shell that runs the command and its hooks: \"default\" for $SHELL, a shell name or path, or \"none\" to execute the command directly")
 		useCLI          = flag.Bool("cli", false, "Use CLI output instead of terminal UI")
-		commandStr      = flag.String("command", "", "Command to benchmark as a quoted string (alternative to positional arguments)")
+		parameterStep   = flag.Float64("parameter-step", 1, "Step size between values of --parameter-scan")
+		setup           = flag.String("setup", "", "Shell command to run once before each command's warmups and runs (untimed)")
+		prepare         = flag.String("prepare", "", "Shell command to run before every warmup and benchmark run (untimed)")
+		cleanup         = flag.String("cleanup", "", "Shell command to run after every warmup and benchmark run (untimed)")
+		ignoreFailure   = flag.Bool("ignore-failure", false, "Count runs that exit with a non-zero code instead of treating them as failed")
+		conclude        = flag.String("conclude", "", "Shell command to run once after each command's runs have finished (untimed)")
+		input           = flag.String("input", "", "File whose contents are sent to the command's stdin, reopened for every run")
+		inputString     = flag.String("input-string", "", "String sent to the command's stdin on every run")
+		stdin           = flag.String("stdin", "null", "Stdin of the command when no input is given: \"null\" or \"inherit\" (CLI only)")
+		outputFlag      = flag.String("output", "pipe", "Where the command's stdout and stderr go: \"pipe\" (read by chrono), \"null\", \"inherit\" (CLI only) or a file path, in which {run} is replaced by the run")
+		envFile         = flag.String("env-file", "", "File of KEY=VALUE lines added to the command's environment (--env takes precedence)")
+		clearEnv        = flag.Bool("clear-env", false, "Start the command with an empty environment, apart from --env and --env-file")
+		cwd             = flag.String("cwd", "", "Working directory of the command, its hooks and calibration")
+		concurrency     = flag.Int("concurrency", 1, "Number of instances of the command kept running at once (needs --cli)")
+		loadDuration    = flag.Duration("duration", 0, "With --concurrency, keep starting runs for this long instead of a number of --runs")
+		pty             = flag.Bool("pty", false, "Run the command with a pseudo-terminal as its stdout and stderr (Linux only)")
+		ptySize         = flag.String("pty-size", "80x24", "Window size of the --pty pseudo-terminal as COLUMNSxROWS")
+		killSignal      = flag.String("kill-signal", "SIGKILL", "Signal sent to stop the command on phrase match, timeout or interrupt (e.g. SIGINT, SIGTERM)")
+		killGrace       = flag.Duration("kill-grace", 2*time.Second, "Time to wait after --kill-signal before escalating to SIGKILL")
 	)
-	flag.Parse()
+	flag.Var(&commandStrs, "command", "Command to benchmark as a quoted string (alternative to positional arguments, repeat to compare commands)")
+	flag.Var(&envStrs, "env", "Set an environment variable for the command as KEY=VALUE (repeatable)")
+	flag.Var(&failPhraseStrs, "fail-phrase", "Fail a run as soon as this phrase appears on stdout or stderr (repeatable)")
+	flag.Var(&failRegexStrs, "fail-phrase-regex", "Fail a run as soon as a line matches this regular expression (repeatable)")
+	flag.Var(&checkpointStrs, "checkpoint", "Record the time to a phrase as NAME=PHRASE (repeat for checkpoints reached in order; the run ends at the last)")
+	flag.Var(&parameterScan, "parameter-scan", "Benchmark every value of a numeric parameter: NAME MIN MAX, substituted for {NAME} in the command")
+	flag.Var(&parameterList, "parameter-list", "Benchmark every value of a parameter: NAME VALUE1,VALUE2,..., substituted for {NAME} in the command")
+
+	args, err := expandMultiValueFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	flag.CommandLine.Parse(args)
 
 	if *versionFlag {
 		fmt.Printf("chrono %s\n", version)
 		os.Exit(0)
 	}
 
-	var command []string
-	if *commandStr != "" {
-		var err error
-		command, err = parseCommandString(*commandStr)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing command string: %v\n", err)
-			os.Exit(1)
-		}
+	var commands [][]string
+	script := len(commandStrs) > 0 && benchmark.ResolveShell(*shell) != ""
+	if len(commandStrs) > 0 {
 		if flag.NArg() > 0 {
 			fmt.Fprintf(os.Stderr, "Error: cannot specify both --command and positional arguments\n")
 			flag.Usage()
 			os.Exit(1)
 		}
+		for _, commandStr := range commandStrs {
+			if script {
+				// The shell parses the command itself, so pipes and
+				// redirects keep their meaning.
+				commands = append(commands, []string{commandStr})
+				continue
+			}
+			command, err := parseCommandString(commandStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing command string: %v\n", err)
+				os.Exit(1)
+			}
+			commands = append(commands, command)
+		}
 	} else {
 		if flag.NArg() == 0 {
 			fmt.Fprintf(os.Stderr, "Error: command to benchmark is required\n")
 			fmt.Fprintf(os.Stderr, "Use either:\n")
-			fmt.Fprintf(os.Stderr, "  --command \"cmd with args\"  (command as quoted string)\n")
+			fmt.Fprintf(os.Stderr, "  --command \"cmd with args\"  (command as quoted string, repeatable)\n")
 			fmt.Fprintf(os.Stderr, "  -- cmd with args           (command after -- separator)\n")
 			fmt.Fprintf(os.Stderr, "  cmd with args              (command as positional arguments)\n")
+			fmt.Fprintf(os.Stderr, "  cmd1 args %s cmd2 args    (several commands to compare)\n", commandSeparator)
 			flag.Usage()
 			os.Exit(1)
 		}
-		command = flag.Args()
+		commands, err = splitCommandGroups(flag.Args())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing commands: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *phrase != "" && *phraseRegex != "" {
+		fmt.Fprintf(os.Stderr, "Error: cannot specify both --phrase and --phrase-regex\n")
+		os.Exit(1)
+	}
+	var phrasePattern *regexp.Regexp
+	if *phraseRegex != "" {
+		pattern := *phraseRegex
+		if *ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		phrasePattern, err = regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --phrase-regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var failRegexes []*regexp.Regexp
+	for _, pattern := range failRegexStrs {
+		if *ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		failRegex, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --fail-phrase-regex: %v\n", err)
+			os.Exit(1)
+		}
+		failRegexes = append(failRegexes, failRegex)
+	}
+	if slices.Contains(failPhraseStrs, "") {
+		fmt.Fprintf(os.Stderr, "Error: --fail-phrase must not be empty\n")
+		os.Exit(1)
+	}
+
+	probe, err := parseProbe(*waitTCP, *waitHTTP, *waitFile, *waitUnixSocket, *waitStatus, *waitInterval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if probe.Kind != benchmark.ProbeNone && (*phrase != "" || *phraseRegex != "" || len(checkpointStrs) > 0) {
+		fmt.Fprintf(os.Stderr, "Error: cannot combine --wait-* with --phrase, --phrase-regex or --checkpoint\n")
+		os.Exit(1)
+	}
+
+	if *startPhrase != "" && *phrase == "" && *phraseRegex == "" && len(checkpointStrs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --start-phrase needs --phrase, --phrase-regex or --checkpoint to end the interval\n")
+		os.Exit(1)
+	}
+
+	phraseStreamMode, err := benchmark.ParsePhraseStream(*phraseStream)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --phrase-stream: %v\n", err)
+		os.Exit(1)
+	}
+	if phraseStreamMode != benchmark.PhraseStreamBoth && *phrase == "" && *phraseRegex == "" && len(checkpointStrs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --phrase-stream needs --phrase, --phrase-regex or --checkpoint\n")
+		os.Exit(1)
+	}
+	if phraseStreamMode != benchmark.PhraseStreamBoth && *pty {
+		fmt.Fprintf(os.Stderr, "Error: --pty merges stdout and stderr, so --phrase-stream must be both\n")
+		os.Exit(1)
+	}
+
+	stdinMode, err := parseStdin(*input, *inputString, *stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if stdinMode == benchmark.StdinInherit && !*useCLI {
+		fmt.Fprintf(os.Stderr, "Error: --stdin inherit reads from the terminal and needs --cli\n")
+		os.Exit(1)
+	}
+	stdinInput := *input
+	if stdinMode == benchmark.StdinString {
+		stdinInput = *inputString
+	}
+
+	outputMode, outputFile := benchmark.ParseOutput(*outputFlag)
+	if outputMode == benchmark.OutputInherit && !*useCLI {
+		fmt.Fprintf(os.Stderr, "Error: --output inherit writes to the terminal and needs --cli\n")
+		os.Exit(1)
+	}
+	if outputMode == benchmark.OutputNull && (*phrase != "" || *phraseRegex != "" || len(checkpointStrs) > 0) {
+		fmt.Fprintf(os.Stderr, "Error: --output null discards the output, so the phrase could never be found\n")
+		os.Exit(1)
+	}
+	if outputMode == benchmark.OutputFile && outputFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: --output requires null, pipe, inherit or a file path\n")
+		os.Exit(1)
+	}
+
+	var env []string
+	if *envFile != "" {
+		if env, err = benchmark.ParseEnvFile(*envFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --env-file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, value := range envStrs {
+		assignment, err := benchmark.ParseEnv(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --env: %v\n", err)
+			os.Exit(1)
+		}
+		env = append(env, assignment)
+	}
+	if *cwd != "" {
+		if info, err := os.Stat(*cwd); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: invalid --cwd: %s is not a directory\n", *cwd)
+			os.Exit(1)
+		}
+	}
+
+	runsConfig, err := parseRuns(*runs, *minRuns, *maxRuns, *targetRSE, *maxTime)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --concurrency must be at least 1\n")
+		os.Exit(1)
+	}
+	if *concurrency > 1 && !*useCLI {
+		fmt.Fprintf(os.Stderr, "Error: --concurrency runs several commands at once and needs --cli\n")
+		os.Exit(1)
+	}
+	if *concurrency > 1 && runsConfig.AutoRuns {
+		fmt.Fprintf(os.Stderr, "Error: cannot combine --concurrency with --runs auto\n")
+		os.Exit(1)
+	}
+	if *loadDuration < 0 || (*loadDuration > 0 && *concurrency == 1) {
+		fmt.Fprintf(os.Stderr, "Error: --duration needs --concurrency greater than 1\n")
+		os.Exit(1)
+	}
+
+	ptyColumns, ptyRows, err := parsePTYSize(*ptySize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --pty-size: %v\n", err)
+		os.Exit(1)
+	}
+
+	checkpoints, err := parseCheckpoints(checkpointStrs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --checkpoint: %v\n", err)
+		os.Exit(1)
+	}
+	if len(checkpoints) > 0 && (*phrase != "" || *phraseRegex != "") {
+		fmt.Fprintf(os.Stderr, "Error: cannot combine --checkpoint with --phrase or --phrase-regex\n")
+		os.Exit(1)
+	}
+
+	killSig, err := benchmark.ParseSignal(*killSignal)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --kill-signal: %v\n", err)
+		os.Exit(1)
+	}
+	if *idleTimeout < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --idle-timeout must not be negative\n")
+		os.Exit(1)
+	}
+	if *untilQuiet < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --until-quiet must not be negative\n")
+		os.Exit(1)
+	}
+	if *untilQuiet > 0 && (*phrase != "" || *phraseRegex != "" || len(checkpoints) > 0 || probe.Kind != benchmark.ProbeNone) {
+		fmt.Fprintf(os.Stderr, "Error: cannot combine --until-quiet with --phrase, --phrase-regex, --checkpoint or --wait-*\n")
+		os.Exit(1)
+	}
+	if *untilQuiet > 0 && *idleTimeout > 0 && *idleTimeout <= *untilQuiet {
+		fmt.Fprintf(os.Stderr, "Error: --idle-timeout must be longer than --until-quiet\n")
+		os.Exit(1)
+	}
+	if *killGrace < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --kill-grace must not be negative\n")
+		os.Exit(1)
+	}
+
+	var parameter *parameterFlag
+	if parameterScan.set {
+		if err := parameterScan.expand(*parameterStep); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		parameter = &parameterScan.parameterFlag
+	}
+	if parameterList.name != "" {
+		if parameter != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot specify both --parameter-scan and --parameter-list\n")
+			os.Exit(1)
+		}
+		parameter = &parameterList.parameterFlag
 	}
 
-	config := benchmark.Config{
+	baseConfig := benchmark.Config{
+		StartPhrase:     *startPhrase,
 		Phrase:          *phrase,
+		PhraseRegex:     phrasePattern,
+		PhraseStream:    phraseStreamMode,
+		FailPhrases:     failPhraseStrs,
+		FailRegexes:     failRegexes,
+		Probe:           probe,
+		IgnoreCase:      *ignoreCase,
+		Checkpoints:     checkpoints,
 		Warmups:         *warmups,
-		Runs:            *runs,
+		Runs:            runsConfig.Runs,
+		AutoRuns:        runsConfig.AutoRuns,
+		MinRuns:         runsConfig.MinRuns,
+		MaxRuns:         runsConfig.MaxRuns,
+		TargetRSE:       runsConfig.TargetRSE,
+		MaxTime:         runsConfig.MaxTime,
+		Concurrency:     *concurrency,
+		LoadDuration:    *loadDuration,
 		Timeout:         *timeout,
+		IdleTimeout:     *idleTimeout,
+		UntilQuiet:      *untilQuiet,
 		CalibrationRuns: *calibrationRuns,
-		SkipCalibration: *skipCalibration,
-		Command:         command,
+		// Calibration measures the spawn cost, which timing from a start
+		// phrase leaves out.
+		SkipCalibration: *skipCalibration || *startPhrase != "",
+		Script:          script,
+		Shell:           benchmark.ResolveShell(*shell),
+		Env:             env,
+		ClearEnv:        *clearEnv,
+		Dir:             *cwd,
+		Stdin:           stdinMode,
+		Input:           stdinInput,
+		PTY:             *pty,
+		PTYColumns:      ptyColumns,
+		PTYRows:         ptyRows,
+		Output:          outputMode,
+		OutputFile:      outputFile,
 		UseCli:          *useCLI,
+		Setup:           *setup,
+		Prepare:         *prepare,
+		Cleanup:         *cleanup,
+		Conclude:        *conclude,
+		IgnoreFailure:   *ignoreFailure,
+		KillSignal:      killSig,
+		KillGrace:       *killGrace,
+	}
+
+	var configs []benchmark.Config
+	for _, command := range commands {
+		if parameter == nil {
+			config := baseConfig
+			config.Command = command
+			configs = append(configs, config)
+			continue
+		}
+
+		for _, value := range parameter.values {
+			substituted, err := substituteParameter(command, parameter.name, value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			config := baseConfig
+			config.Command = substituted
+			config.ParameterName = parameter.name
+			config.ParameterValue = value
+			configs = append(configs, config)
+		}
 	}
 
-	return config
+	return configs
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type parameterFlag struct {
+	name   string
+	values []string
+}
+
+type parameterScanFlag struct {
+	parameterFlag
+	min, max float64
+	set      bool
+}
+
+func (p *parameterScanFlag) String() string {
+	if !p.set {
+		return ""
+	}
+	return fmt.Sprintf("%s %s %s", p.name, formatParameterValue(p.min), formatParameterValue(p.max))
+}
+
+func (p *parameterScanFlag) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return fmt.Errorf("expected NAME MIN MAX, got %q", value)
+	}
+
+	min, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid minimum %q", fields[1])
+	}
+	max, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return fmt.Errorf("invalid maximum %q", fields[2])
+	}
+	if min > max {
+		return fmt.Errorf("minimum %s is greater than maximum %s", fields[1], fields[2])
+	}
+
+	p.name = fields[0]
+	p.min = min
+	p.max = max
+	p.set = true
+	return nil
+}
+
+func (p *parameterScanFlag) expand(step float64) error {
+	if step <= 0 {
+		return fmt.Errorf("parameter step must be positive")
+	}
+
+	const epsilon = 1e-9
+	p.values = nil
+	for i := 0; ; i++ {
+		value := p.min + float64(i)*step
+		if value > p.max+epsilon {
+			break
+		}
+		p.values = append(p.values, formatParameterValue(value))
+	}
+	return nil
+}
+
+type parameterListFlag struct {
+	parameterFlag
+}
+
+func (p *parameterListFlag) String() string {
+	if p.name == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s %s", p.name, strings.Join(p.values, ","))
+}
+
+func (p *parameterListFlag) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return fmt.Errorf("expected NAME VALUES, got %q", value)
+	}
+
+	values := strings.Split(fields[1], ",")
+	for _, v := range values {
+		if v == "" {
+			return fmt.Errorf("empty value in list %q", fields[1])
+		}
+	}
+
+	p.name = fields[0]
+	p.values = values
+	return nil
+}
+
+func formatParameterValue(value float64) string {
+	const precision = 1e9
+	return strconv.FormatFloat(math.Round(value*precision)/precision, 'f', -1, 64)
+}
+
+var multiValueFlags = map[string]int{
+	"parameter-scan": 3,
+	"parameter-list": 2,
+}
+
+// expandMultiValueFlags joins the space separated values of flags such as
+// --parameter-scan NAME MIN MAX into a single argument the flag package can
+// parse. Like the flag package, it stops at the first argument that is not a
+// flag, so the benchmarked command's own arguments are left alone.
+func expandMultiValueFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			return append(expanded, args[i:]...), nil
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		count, ok := multiValueFlags[name]
+		if !ok {
+			expanded = append(expanded, arg)
+			if takesValue(flags, name) && i+1 < len(args) {
+				i++
+				expanded = append(expanded, args[i])
+			}
+			continue
+		}
+
+		if i+count >= len(args) {
+			return nil, fmt.Errorf("flag %s requires %d values", arg, count)
+		}
+		expanded = append(expanded, fmt.Sprintf("--%s=%s", name, strings.Join(args[i+1:i+1+count], " ")))
+		i += count
+	}
+	return expanded, nil
+}
+
+// takesValue reports whether the flag named in an argument is followed by its
+// value as the next argument, rather than being a boolean or written NAME=VALUE.
+func takesValue(flags *flag.FlagSet, name string) bool {
+	if strings.Contains(name, "=") {
+		return false
+	}
+	f := flags.Lookup(name)
+	if f == nil {
+		return false
+	}
+	if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
+func substituteParameter(command []string, name, value string) ([]string, error) {
+	placeholder := "{" + name + "}"
+	substituted := make([]string, len(command))
+	found := false
+	for i, arg := range command {
+		if strings.Contains(arg, placeholder) {
+			found = true
+		}
+		substituted[i] = strings.ReplaceAll(arg, placeholder, value)
+	}
+	if !found {
+		return nil, fmt.Errorf("command does not contain the %s placeholder", placeholder)
+	}
+	return substituted, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestExpandMultiValueFlags(t *testing.T) {
+	flags := flag.NewFlagSet("chrono", flag.ContinueOnError)
+	flags.Int("runs", 10, "")
+	flags.Bool("cli", false, "")
+	flags.Var(&parameterScanFlag{}, "parameter-scan", "")
+	flags.Var(&parameterListFlag{}, "parameter-list", "")
+
+	t.Run("parameter scan", func(t *testing.T) {
+		args, err := expandMultiValueFlags(flags, []string{"--runs", "2", "--parameter-scan", "threads", "1", "4", "echo", "{threads}"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"--runs", "2", "--parameter-scan=threads 1 4", "echo", "{threads}"}
+		if !slices.Equal(args, expected) {
+			t.Errorf("Expected %q, got %q", expected, args)
+		}
+	})
+
+	t.Run("parameter list with single dash", func(t *testing.T) {
+		args, err := expandMultiValueFlags(flags, []string{"-parameter-list", "size", "1k,1M", "echo"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"--parameter-list=size 1k,1M", "echo"}
+		if !slices.Equal(args, expected) {
+			t.Errorf("Expected %q, got %q", expected, args)
+		}
+	})
+
+	t.Run("stops at separator", func(t *testing.T) {
+		input := []string{"--", "echo", "--parameter-list", "a", "b"}
+		args, err := expandMultiValueFlags(flags, input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !slices.Equal(args, input) {
+			t.Errorf("Expected arguments after -- to be untouched, got %q", args)
+		}
+	})
+
+	t.Run("stops at the command", func(t *testing.T) {
+		args, err := expandMultiValueFlags(flags, []string{"--cli", "--runs", "2", "--parameter-list", "n", "1,2", "tool", "--parameter-list", "x", "y", "{n}"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"--cli", "--runs", "2", "--parameter-list=n 1,2", "tool", "--parameter-list", "x", "y", "{n}"}
+		if !slices.Equal(args, expected) {
+			t.Errorf("Expected the command's own flags to be untouched, got %q", args)
+		}
+	})
+
+	t.Run("missing values", func(t *testing.T) {
+		if _, err := expandMultiValueFlags(flags, []string{"--parameter-scan", "threads", "1"}); err == nil {
+			t.Error("Expected error for missing values")
+		}
+	})
+}
+
+func TestParameterScanFlag(t *testing.T) {
+	t.Run("integer range", func(t *testing.T) {
+		var scan parameterScanFlag
+		if err := scan.Set("threads 1 4"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := scan.expand(1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"1", "2", "3", "4"}
+		if !slices.Equal(scan.values, expected) {
+			t.Errorf("Expected %q, got %q", expected, scan.values)
+		}
+	})
+
+	t.Run("fractional step", func(t *testing.T) {
+		var scan parameterScanFlag
+		if err := scan.Set("delay 0 0.3"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := scan.expand(0.1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"0", "0.1", "0.2", "0.3"}
+		if !slices.Equal(scan.values, expected) {
+			t.Errorf("Expected %q, got %q", expected, scan.values)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		var scan parameterScanFlag
+		if err := scan.Set("threads 8 1"); err == nil {
+			t.Error("Expected error when minimum exceeds maximum")
+		}
+		if err := scan.Set("threads one 8"); err == nil {
+			t.Error("Expected error for non-numeric minimum")
+		}
+	})
+}
+
+func TestSubstituteParameter(t *testing.T) {
+	command, err := substituteParameter([]string{"sort", "--parallel={threads}", "file-{threads}"}, "threads", "4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"sort", "--parallel=4", "file-4"}
+	if !slices.Equal(command, expected) {
+		t.Errorf("Expected %q, got %q", expected, command)
+	}
+
+	if _, err := substituteParameter([]string{"sort"}, "threads", "4"); err == nil {
+		t.Error("Expected error when placeholder is missing")
+	}
+}
@@ -0,0 +1,91 @@
+package benchmark
+
+import (
+	"fmt"
+	"time"
+
+	"chrono/internal/stats"
+)
+
+// StopReason says why a series of benchmark runs ended.
+type StopReason int
+
+const (
+	StopNone StopReason = iota
+	StopRuns
+	StopPrecision
+	StopMaxRuns
+	StopMaxTime
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopRuns:
+		return "all runs completed"
+	case StopPrecision:
+		return "target precision reached"
+	case StopMaxRuns:
+		return "--max-runs reached"
+	case StopMaxTime:
+		return "--max-time reached"
+	default:
+		return "running"
+	}
+}
+
+// Precision returns the relative standard error of the mean of the counted
+// runs, once there are at least two of them.
+func Precision(results []Result) (float64, bool) {
+	var durations []time.Duration
+	for _, result := range results {
+		if result.Found {
+			durations = append(durations, result.Duration)
+		}
+	}
+	return stats.RelativeStandardError(durations)
+}
+
+// StopReason decides after every benchmark run whether the series is over,
+// given its results so far and the time since its first run started. A fixed
+// number of runs stops once they are done; with AutoRuns the series stops
+// once the precision reaches TargetRSE or a budget is used up, but never
+// before MinRuns.
+func (c Config) StopReason(results []Result, elapsed time.Duration) StopReason {
+	if !c.AutoRuns {
+		if len(results) >= c.Runs {
+			return StopRuns
+		}
+		return StopNone
+	}
+
+	switch {
+	case len(results) < c.MinRuns:
+		return StopNone
+	case len(results) >= c.MaxRuns:
+		return StopMaxRuns
+	}
+	if rse, ok := Precision(results); ok && rse <= c.TargetRSE {
+		return StopPrecision
+	}
+	if c.MaxTime > 0 && elapsed >= c.MaxTime {
+		return StopMaxTime
+	}
+	return StopNone
+}
+
+// RunsString describes the number of runs, for display.
+func (c Config) RunsString() string {
+	if !c.AutoRuns {
+		return fmt.Sprint(c.Runs)
+	}
+	budget := ""
+	if c.MaxTime > 0 {
+		budget = fmt.Sprintf(", at most %s", c.MaxTime)
+	}
+	return fmt.Sprintf("auto (%d–%d runs until RSE ≤ %s%s)", c.MinRuns, c.MaxRuns, FormatRSE(c.TargetRSE), budget)
+}
+
+// FormatRSE formats a relative standard error as a percentage.
+func FormatRSE(rse float64) string {
+	return fmt.Sprintf("%.2f%%", rse*100)
+}
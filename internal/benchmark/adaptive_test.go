@@ -0,0 +1,44 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStopReason(t *testing.T) {
+	durations := func(values ...time.Duration) []Result {
+		results := make([]Result, len(values))
+		for i, value := range values {
+			results[i] = Result{Duration: value, Found: true, Status: StatusSuccess}
+		}
+		return results
+	}
+	noisy := durations(time.Second, 2*time.Second, 3*time.Second)
+	steady := durations(time.Second, time.Second, time.Second)
+	auto := Config{AutoRuns: true, MinRuns: 3, MaxRuns: 5, TargetRSE: 0.01, MaxTime: time.Minute}
+
+	tests := []struct {
+		name     string
+		config   Config
+		results  []Result
+		elapsed  time.Duration
+		expected StopReason
+	}{
+		{"fixed runs remaining", Config{Runs: 3}, steady[:2], 0, StopNone},
+		{"fixed runs done", Config{Runs: 3}, steady, 0, StopRuns},
+		{"below min runs", auto, steady[:2], time.Hour, StopNone},
+		{"precise", auto, steady, 0, StopPrecision},
+		{"imprecise", auto, noisy, 0, StopNone},
+		{"max time", auto, noisy, time.Minute, StopMaxTime},
+		{"max runs", auto, append(noisy, noisy[:2]...), 0, StopMaxRuns},
+		{"failures are not precise", auto, []Result{{}, {}, {}}, 0, StopNone},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if reason := test.config.StopReason(test.results, test.elapsed); reason != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, reason)
+			}
+		})
+	}
+}
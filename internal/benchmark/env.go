@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ParseEnv checks a KEY=VALUE environment variable assignment.
+func ParseEnv(value string) (string, error) {
+	key, _, ok := strings.Cut(value, "=")
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	return value, nil
+}
+
+// ParseEnvFile reads KEY=VALUE assignments from a file, one per line. Blank
+// lines and lines starting with # are ignored, an "export " prefix is allowed
+// and values may be wrapped in single or double quotes.
+func ParseEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		assignment, err := ParseEnv(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+		key, value, _ := strings.Cut(assignment, "=")
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, scanner.Err()
+}
+
+// environ returns the environment of commands, or nil to inherit chrono's own.
+// Later assignments of the same variable take precedence.
+func (c Config) environ() []string {
+	if len(c.Env) == 0 && !c.ClearEnv {
+		return nil
+	}
+	env := []string{}
+	if !c.ClearEnv {
+		env = os.Environ()
+	}
+	return append(env, c.Env...)
+}
+
+// applyEnvironment sets the environment and working directory of a command
+// chrono starts, whether it is benchmarked, calibrated or a hook.
+func (c Config) applyEnvironment(cmd *exec.Cmd) {
+	cmd.Env = c.environ()
+	cmd.Dir = c.Dir
+}
+
+// EnvString describes how the environment differs from chrono's own, with the
+// effective value of every variable that was set, for the summary. It is
+// empty when commands inherit the environment unchanged.
+func (c Config) EnvString() string {
+	var changes []string
+	if c.ClearEnv {
+		changes = append(changes, "cleared")
+	}
+
+	positions := make(map[string]int)
+	for _, assignment := range c.Env {
+		key, _, _ := strings.Cut(assignment, "=")
+		if i, ok := positions[key]; ok {
+			changes[i] = assignment
+			continue
+		}
+		positions[key] = len(changes)
+		changes = append(changes, assignment)
+	}
+	return strings.Join(changes, ", ")
+}
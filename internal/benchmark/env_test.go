@@ -0,0 +1,90 @@
+package benchmark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.env")
+	contents := "# comment\n\nRUST_LOG=off\nexport GOMAXPROCS=4\nGREETING=\"hello world\"\nEMPTY=\n"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := ParseEnvFile(path)
+	if err != nil {
+		t.Fatalf("Expected env file to parse, got %v", err)
+	}
+	expected := []string{"RUST_LOG=off", "GOMAXPROCS=4", "GREETING=hello world", "EMPTY="}
+	if !slices.Equal(env, expected) {
+		t.Errorf("Expected %q, got %q", expected, env)
+	}
+
+	if err := os.WriteFile(path, []byte("RUST_LOG=off\nnot an assignment\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEnvFile(path); err == nil {
+		t.Error("Expected an error for a line without =")
+	}
+}
+
+func TestEnvString(t *testing.T) {
+	config := Config{Env: []string{"A=1", "B=2", "A=3"}, ClearEnv: true}
+	if got := config.EnvString(); got != "cleared, A=3, B=2" {
+		t.Errorf("Expected effective deltas, got %q", got)
+	}
+	if got := (Config{}).EnvString(); got != "" {
+		t.Errorf("Expected no deltas, got %q", got)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHRONO_INHERITED", "yes")
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"inherited", Config{}, "yes  " + dir},
+		{"set", Config{Env: []string{"CHRONO_SET=1", "CHRONO_SET=2"}}, "yes 2 " + dir},
+		{"cleared", Config{Env: []string{"CHRONO_SET=1"}, ClearEnv: true}, " 1 " + dir},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Dir = dir
+			config.Shell = "/bin/sh"
+			config.Script = true
+			config.Command = []string{`echo "$CHRONO_INHERITED $CHRONO_SET $(pwd -P)"`}
+
+			lines := make(chan Line, 10)
+			if result, err := StreamContext(context.Background(), config, 0, lines); err != nil || !result.Found {
+				t.Fatalf("Expected success, got %+v (%v)", result, err)
+			}
+			var output []string
+			for line := range lines {
+				output = append(output, line.Text)
+			}
+			expected := []string{test.expected}
+			if !slices.Equal(output, expected) {
+				t.Errorf("Expected %q, got %q", expected, output)
+			}
+
+			config.Setup = config.Command[0]
+			hookOutput, err := RunHook(config, HookSetup)
+			if err != nil || hookOutput != expected[0]+"\n" {
+				t.Errorf("Expected the hook to share the environment, got %q (%v)", hookOutput, err)
+			}
+		})
+	}
+}
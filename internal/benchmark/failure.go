@@ -0,0 +1,95 @@
+package benchmark
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HasFailPhrases reports whether runs fail as soon as a failure phrase
+// appears in the output.
+func (c Config) HasFailPhrases() bool {
+	return len(c.FailPhrases) > 0 || len(c.FailRegexes) > 0
+}
+
+// FailPhrasesString describes the failure phrases for display, quoting plain
+// phrases and wrapping regular expressions in slashes.
+func (c Config) FailPhrasesString() string {
+	phrases := make([]string, 0, len(c.FailPhrases)+len(c.FailRegexes))
+	for _, phrase := range c.FailPhrases {
+		phrases = append(phrases, fmt.Sprintf("%q", phrase))
+	}
+	for _, pattern := range c.FailRegexes {
+		phrases = append(phrases, "/"+pattern.String()+"/")
+	}
+	return strings.Join(phrases, ", ")
+}
+
+// matchFailPhrase returns the failure phrase in line, as FailPhrasesString
+// shows it, if there is one.
+func (c Config) matchFailPhrase(line string) (string, bool) {
+	for _, phrase := range c.FailPhrases {
+		if c.containsPhrase(line, phrase) {
+			return fmt.Sprintf("%q", phrase), true
+		}
+	}
+	for _, pattern := range c.FailRegexes {
+		if pattern.MatchString(line) {
+			return "/" + pattern.String() + "/", true
+		}
+	}
+	return "", false
+}
+
+// Cause is a short description of why a run failed, the same for every run
+// that failed the same way. It is empty for successful runs.
+func (r Result) Cause() string {
+	switch r.Status {
+	case StatusSuccess:
+		return ""
+	case StatusNonZeroExit:
+		if r.Signal != "" {
+			return "signal " + r.Signal
+		}
+		return fmt.Sprintf("exit %d", r.ExitCode)
+	case StatusFailPhrase:
+		return "failure phrase " + r.FailPhrase
+	default:
+		return r.Status.String()
+	}
+}
+
+type FailureCause struct {
+	Cause string
+	Count int
+}
+
+// FailureCauses counts the runs in results that did not count by cause, most
+// common first.
+func FailureCauses(results []Result) []FailureCause {
+	var causes []FailureCause
+	for _, result := range results {
+		if result.Found {
+			continue
+		}
+		cause := result.Cause()
+		i := slices.IndexFunc(causes, func(c FailureCause) bool { return c.Cause == cause })
+		if i < 0 {
+			causes = append(causes, FailureCause{Cause: cause})
+			i = len(causes) - 1
+		}
+		causes[i].Count++
+	}
+	slices.SortStableFunc(causes, func(a, b FailureCause) int { return b.Count - a.Count })
+	return causes
+}
+
+// FormatFailureCauses describes the causes for display, such as
+// `failure phrase "panic:" (3), exit 1 (1)`.
+func FormatFailureCauses(causes []FailureCause) string {
+	described := make([]string, len(causes))
+	for i, cause := range causes {
+		described[i] = fmt.Sprintf("%s (%d)", cause.Cause, cause.Count)
+	}
+	return strings.Join(described, ", ")
+}
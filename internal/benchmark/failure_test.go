@@ -0,0 +1,110 @@
+package benchmark
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFailPhrase(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		failure string
+		stream  LineKind
+	}{
+		{
+			name:    "completion",
+			config:  Config{Command: []string{"sh", "-c", "echo starting; echo 'panic: oops' >&2; sleep 5"}, FailPhrases: []string{"panic:"}},
+			failure: `"panic:"`,
+			stream:  LineStderr,
+		},
+		{
+			name: "phrase on another stream",
+			config: Config{
+				Command:      []string{"sh", "-c", "echo 'listen: Address already in use' >&2; sleep 0.1; echo ready; sleep 5"},
+				Phrase:       "ready",
+				PhraseStream: PhraseStreamStdout,
+				FailRegexes:  []*regexp.Regexp{regexp.MustCompile(`(?i)address already in use`)},
+			},
+			failure: "/(?i)address already in use/",
+			stream:  LineStderr,
+		},
+		{
+			name:    "ignoring case",
+			config:  Config{Command: []string{"sh", "-c", "echo FATAL error; sleep 5"}, Phrase: "ready", FailPhrases: []string{"fatal"}, IgnoreCase: true},
+			failure: `"fatal"`,
+			stream:  LineStdout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Timeout = 5 * time.Second
+
+			lines := make(chan Line, 10)
+			start := time.Now()
+			result, err := StreamContext(context.Background(), config, 0, lines)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the run to stop at the failure phrase, took %v", elapsed)
+			}
+			if result.Found || result.Status != StatusFailPhrase {
+				t.Fatalf("Expected the run to fail, got %+v", result)
+			}
+			if result.FailPhrase != tt.failure || result.MatchStream != tt.stream {
+				t.Errorf("Expected %s on %s, got %s on %s", tt.failure, tt.stream, result.FailPhrase, result.MatchStream)
+			}
+
+			var marker string
+			for line := range lines {
+				if line.Kind == LineFailure {
+					marker = line.Text
+				}
+			}
+			if expected := "Failure phrase found! " + tt.failure + " on " + tt.stream.String(); marker != expected {
+				t.Errorf("Expected marker %q, got %q", expected, marker)
+			}
+		})
+	}
+
+	config := Config{Command: []string{"sh", "-c", "echo ok"}, FailPhrases: []string{"panic:"}, Output: OutputNull}
+	if result := Run(config, 0); !result.Found {
+		t.Errorf("Expected a run without a failure phrase to count, got %+v", result)
+	}
+}
+
+func TestFailureCauses(t *testing.T) {
+	results := []Result{
+		{Found: true, Status: StatusSuccess},
+		{Status: StatusNonZeroExit, ExitCode: 1},
+		{Status: StatusFailPhrase, FailPhrase: `"panic:"`},
+		{Status: StatusFailPhrase, FailPhrase: `"panic:"`},
+		{Status: StatusTimeout},
+		{Found: true, Status: StatusNonZeroExit, ExitCode: 2},
+	}
+
+	causes := FailureCauses(results)
+	expected := []FailureCause{{`failure phrase "panic:"`, 2}, {"exit 1", 1}, {"timed out", 1}}
+	if len(causes) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, causes)
+	}
+	for i := range expected {
+		if causes[i] != expected[i] {
+			t.Errorf("Expected cause %d to be %v, got %v", i, expected[i], causes[i])
+		}
+	}
+	if described := FormatFailureCauses(causes); described != `failure phrase "panic:" (2), exit 1 (1), timed out (1)` {
+		t.Errorf("Unexpected description %q", described)
+	}
+	if description := DescribeFailures(results[1:5], Config{}); description != "all runs failed: "+FormatFailureCauses(causes) {
+		t.Errorf("Unexpected failure description %q", description)
+	}
+	if description := DescribeFailures(nil, Config{}); description != "no runs completed" {
+		t.Errorf("Unexpected description without results %q", description)
+	}
+}
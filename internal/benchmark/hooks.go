@@ -0,0 +1,86 @@
+package benchmark
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type Hook int
+
+const (
+	HookSetup Hook = iota
+	HookPrepare
+	HookCleanup
+	HookConclude
+)
+
+func (h Hook) String() string {
+	switch h {
+	case HookSetup:
+		return "setup"
+	case HookPrepare:
+		return "prepare"
+	case HookCleanup:
+		return "cleanup"
+	case HookConclude:
+		return "conclude"
+	default:
+		return "unknown"
+	}
+}
+
+func (c Config) HookCommand(hook Hook) string {
+	switch hook {
+	case HookSetup:
+		return c.Setup
+	case HookPrepare:
+		return c.Prepare
+	case HookCleanup:
+		return c.Cleanup
+	case HookConclude:
+		return c.Conclude
+	default:
+		return ""
+	}
+}
+
+type HookError struct {
+	Hook   Hook
+	Err    error
+	Output string
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %v", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// RunHook runs the configured command for hook through the command's shell, or
+// the user's when the command is executed directly, with the command's
+// environment and working directory, and returns its combined output. Hooks
+// are never timed. A hook that is not configured succeeds without doing
+// anything.
+func RunHook(config Config, hook Hook) (string, error) {
+	command := config.HookCommand(hook)
+	if command == "" {
+		return "", nil
+	}
+
+	shell := config.Shell
+	if shell == "" {
+		shell = defaultShell()
+	}
+	cmd := exec.Command(shell, "-c", command)
+	config.applyEnvironment(cmd)
+	configureProcess(cmd)
+	output, err := cmd.CombinedOutput()
+	processExited(cmd)
+	if err != nil {
+		return string(output), &HookError{Hook: hook, Err: err, Output: strings.TrimRight(string(output), "\n")}
+	}
+	return string(output), nil
+}
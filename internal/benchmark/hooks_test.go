@@ -0,0 +1,69 @@
+package benchmark
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHook(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		output, err := RunHook(Config{}, HookPrepare)
+		if err != nil {
+			t.Errorf("Expected no error for unconfigured hook, got %v", err)
+		}
+		if output != "" {
+			t.Errorf("Expected no output, got %q", output)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		config := Config{Setup: "echo ready"}
+		output, err := RunHook(config, HookSetup)
+		if err != nil {
+			t.Fatalf("Expected hook to succeed, got %v", err)
+		}
+		if strings.TrimSpace(output) != "ready" {
+			t.Errorf("Expected hook output, got %q", output)
+		}
+	})
+
+	t.Run("command's shell", func(t *testing.T) {
+		shell := filepath.Join(t.TempDir(), "shell")
+		if err := os.WriteFile(shell, []byte("#!/bin/sh\necho \"shell ran $2\"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		config := Config{Shell: shell, Prepare: "true"}
+		output, err := RunHook(config, HookPrepare)
+		if err != nil {
+			t.Fatalf("Expected hook to succeed, got %v", err)
+		}
+		if strings.TrimSpace(output) != "shell ran true" {
+			t.Errorf("Expected the hook to run through the command's shell, got %q", output)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		config := Config{Cleanup: "echo broken >&2; exit 3"}
+		_, err := RunHook(config, HookCleanup)
+		if err == nil {
+			t.Fatal("Expected hook to fail")
+		}
+
+		var hookErr *HookError
+		if !errors.As(err, &hookErr) {
+			t.Fatalf("Expected HookError, got %T", err)
+		}
+		if hookErr.Hook != HookCleanup {
+			t.Errorf("Expected cleanup hook, got %v", hookErr.Hook)
+		}
+		if hookErr.Output != "broken" {
+			t.Errorf("Expected captured output, got %q", hookErr.Output)
+		}
+		if !strings.Contains(err.Error(), "cleanup hook failed") {
+			t.Errorf("Expected hook name in error, got %v", err)
+		}
+	})
+}
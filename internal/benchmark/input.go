@@ -0,0 +1,51 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type StdinMode int
+
+const (
+	StdinNull StdinMode = iota
+	StdinFile
+	StdinString
+	StdinInherit
+)
+
+// openStdin returns the reader to connect to the command's stdin for a single
+// run. Input files are opened afresh for every run so that each one reads
+// the whole file; the returned function releases the file.
+func (c Config) openStdin() (io.Reader, func(), error) {
+	switch c.Stdin {
+	case StdinFile:
+		file, err := os.Open(c.Input)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening input: %w", err)
+		}
+		return file, func() { file.Close() }, nil
+	case StdinString:
+		return strings.NewReader(c.Input), func() {}, nil
+	case StdinInherit:
+		return os.Stdin, func() {}, nil
+	default:
+		return nil, func() {}, nil
+	}
+}
+
+// StdinString describes where the command's stdin comes from, for display.
+func (c Config) StdinString() string {
+	switch c.Stdin {
+	case StdinFile:
+		return c.Input
+	case StdinString:
+		return fmt.Sprintf("%q", c.Input)
+	case StdinInherit:
+		return "inherit"
+	default:
+		return "null"
+	}
+}
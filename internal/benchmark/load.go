@@ -0,0 +1,121 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LoadResult is the outcome of running a command concurrently.
+type LoadResult struct {
+	Results []Result
+	Elapsed time.Duration
+}
+
+// Throughput is the number of counted runs completed per second of wall time.
+func (r LoadResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	counted := 0
+	for _, result := range r.Results {
+		if result.Found {
+			counted++
+		}
+	}
+	return float64(counted) / r.Elapsed.Seconds()
+}
+
+// RunLoad keeps config.Concurrency instances of the command running until
+// config.Runs runs have started or, with config.LoadDuration, until that much
+// time has passed. The prepare and cleanup hooks run around every run.
+// onResult is called with the number and result of every run as it finishes,
+// never concurrently. It stops early when a hook fails, the command cannot be
+// run or ctx is cancelled; runs cancelled by ctx are not included.
+func RunLoad(ctx context.Context, config Config, shellOverhead time.Duration, onResult func(run int, result Result)) (LoadResult, error) {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var (
+		mu      sync.Mutex
+		started int
+		load    = LoadResult{Results: make([]Result, 0, max(config.Runs, 0))}
+		loadErr error
+	)
+	start := time.Now()
+
+	// next hands out run numbers until the runs or the duration are used up.
+	next := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return 0, false
+		}
+		if config.LoadDuration > 0 {
+			if time.Since(start) >= config.LoadDuration {
+				return 0, false
+			}
+		} else if started >= config.Runs {
+			return 0, false
+		}
+		started++
+		return started, true
+	}
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if loadErr == nil {
+			loadErr = err
+		}
+		stop()
+	}
+
+	var workers sync.WaitGroup
+	for range max(config.Concurrency, 1) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				run, ok := next()
+				if !ok {
+					return
+				}
+				if _, err := RunHook(config, HookPrepare); err != nil {
+					fail(err)
+					return
+				}
+
+				runConfig := config
+				runConfig.RunLabel = RunLabel(false, run)
+				result, runErr := RunContext(ctx, runConfig, shellOverhead)
+				if ctx.Err() == nil {
+					mu.Lock()
+					load.Results = append(load.Results, result)
+					if onResult != nil {
+						onResult(run, result)
+					}
+					mu.Unlock()
+				}
+
+				if _, err := RunHook(config, HookCleanup); err != nil {
+					fail(err)
+					return
+				}
+				if runErr != nil && ctx.Err() == nil {
+					fail(runErr)
+					return
+				}
+			}
+		}()
+	}
+	workers.Wait()
+
+	load.Elapsed = time.Since(start)
+	if loadErr != nil {
+		return load, loadErr
+	}
+	// The internal context is only cancelled by fail, so any remaining
+	// error comes from the caller's.
+	return load, ctx.Err()
+}
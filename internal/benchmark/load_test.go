@@ -0,0 +1,77 @@
+package benchmark
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRunLoad(t *testing.T) {
+	t.Run("runs in parallel", func(t *testing.T) {
+		config := Config{Command: []string{"sleep", "0.2"}, Runs: 8, Concurrency: 4}
+		var runs []int
+		load, err := RunLoad(context.Background(), config, 0, func(run int, result Result) {
+			runs = append(runs, run)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(load.Results) != 8 {
+			t.Fatalf("Expected 8 results, got %d", len(load.Results))
+		}
+		sort.Ints(runs)
+		for i, run := range runs {
+			if run != i+1 {
+				t.Fatalf("Expected every run to be reported once, got %v", runs)
+			}
+		}
+		// Eight 200ms runs four at a time take two rounds, not eight.
+		if load.Elapsed >= time.Second {
+			t.Errorf("Expected runs to overlap, took %v", load.Elapsed)
+		}
+		if throughput := load.Throughput(); throughput < 8 || throughput > 40 {
+			t.Errorf("Expected a throughput of about 20 runs/s, got %.2f", throughput)
+		}
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		config := Config{Command: []string{"sleep", "0.1"}, Concurrency: 2, LoadDuration: 250 * time.Millisecond}
+		load, err := RunLoad(context.Background(), config, 0, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(load.Results) < 4 || len(load.Results) > 8 {
+			t.Errorf("Expected about 6 runs in 250ms, got %d", len(load.Results))
+		}
+	})
+
+	t.Run("no runs", func(t *testing.T) {
+		config := Config{Command: []string{"true"}, Runs: -2, Concurrency: 2}
+		load, err := RunLoad(context.Background(), config, 0, nil)
+		if err != nil || len(load.Results) != 0 {
+			t.Errorf("Expected no runs, got %d results (%v)", len(load.Results), err)
+		}
+	})
+
+	t.Run("start failure stops the load", func(t *testing.T) {
+		config := Config{Command: []string{"/nonexistent/command"}, Runs: 100, Concurrency: 2}
+		load, err := RunLoad(context.Background(), config, 0, nil)
+		if err == nil {
+			t.Fatal("Expected the start failure to be returned")
+		}
+		if len(load.Results) > 2 {
+			t.Errorf("Expected the load to stop after the failure, got %d results", len(load.Results))
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		config := Config{Command: []string{"sleep", "5"}, Runs: 4, Concurrency: 4}
+		load, err := RunLoad(ctx, config, 0, nil)
+		if err == nil || len(load.Results) != 0 {
+			t.Errorf("Expected cancellation without results, got %d results (%v)", len(load.Results), err)
+		}
+	})
+}
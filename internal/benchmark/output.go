@@ -0,0 +1,132 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// OutputMode says where the command's stdout and stderr go. Every mode has a
+// different cost that is included in the timings, so the same mode is used
+// whichever front end runs the benchmark.
+type OutputMode int
+
+const (
+	OutputPipe OutputMode = iota
+	OutputNull
+	OutputInherit
+	OutputFile
+)
+
+// ParseOutput parses the value of --output: null, pipe, inherit, or otherwise
+// the path of a file, in which {run} is replaced by the run.
+func ParseOutput(value string) (OutputMode, string) {
+	switch value {
+	case "pipe":
+		return OutputPipe, ""
+	case "null":
+		return OutputNull, ""
+	case "inherit":
+		return OutputInherit, ""
+	default:
+		return OutputFile, value
+	}
+}
+
+// RunLabel identifies a run in output file names: "1", "2", ... for benchmark
+// runs and "warmup-1", "warmup-2", ... for warmups.
+func RunLabel(isWarmup bool, run int) string {
+	if isWarmup {
+		return fmt.Sprintf("warmup-%d", run)
+	}
+	return fmt.Sprint(run)
+}
+
+// OutputPath returns the file a run's output is written to.
+func (c Config) OutputPath() string {
+	return strings.ReplaceAll(c.OutputFile, "{run}", c.RunLabel)
+}
+
+// OutputString describes where the output goes and what that costs, for the
+// summary.
+func (c Config) OutputString() string {
+	if c.PTY {
+		return c.ptyOutputString()
+	}
+	switch c.Output {
+	case OutputNull:
+		if reasons := c.pipeReasons(); len(reasons) > 0 {
+			return fmt.Sprintf("piped for %s and then discarded (null), timings include the pipe and chrono reading it", strings.Join(reasons, ", "))
+		}
+		return "discarded (null), the cheapest option"
+	case OutputInherit:
+		return "written to the terminal (inherit), timings include terminal rendering"
+	case OutputFile:
+		return fmt.Sprintf("written to %s, timings include writing the file", c.OutputFile)
+	default:
+		return "read through a pipe (pipe), timings include the pipe and chrono reading it"
+	}
+}
+
+func (c Config) ptyOutputString() string {
+	description := fmt.Sprintf("read from a %dx%d pseudo-terminal (pty)", c.PTYColumns, c.PTYRows)
+	switch c.Output {
+	case OutputInherit:
+		description += " and copied to the terminal"
+	case OutputFile:
+		description += fmt.Sprintf(" and copied to %s", c.OutputFile)
+	}
+	return description + ", timings include the terminal and chrono reading it"
+}
+
+// needsPipe reports whether the output has to be read by chrono: to look for
+// the phrase or failure phrases, to notice it stalling or going quiet, because
+// it is piped, or because it comes from a pseudo-terminal.
+func (c Config) needsPipe() bool {
+	return c.HasPhrase() || len(c.pipeReasons()) > 0 || c.Output == OutputPipe || c.PTY
+}
+
+// pipeReasons lists the options that make chrono read the output, so that it
+// is piped even with --output null. A phrase is not one of them, since it
+// cannot be combined with --output null.
+func (c Config) pipeReasons() []string {
+	var reasons []string
+	if c.HasFailPhrases() {
+		reasons = append(reasons, "--fail-phrase")
+	}
+	if c.IdleTimeout > 0 {
+		reasons = append(reasons, "--idle-timeout")
+	}
+	if c.UntilQuiet > 0 {
+		reasons = append(reasons, "--until-quiet")
+	}
+	return reasons
+}
+
+// attachOutput connects the command's output to the pipes, or the
+// pseudo-terminal, that chrono reads.
+func (c Config) attachOutput(cmd *exec.Cmd) (*outputStreams, error) {
+	if c.PTY {
+		return attachPTY(cmd, c)
+	}
+	return attachOutputStreams(cmd)
+}
+
+// openOutput returns the writer that receives the command's output for a
+// single run, nil for the null device. The returned function releases it.
+func (c Config) openOutput() (stdout, stderr io.Writer, closeOutput func(), err error) {
+	switch c.Output {
+	case OutputInherit:
+		return os.Stdout, os.Stderr, func() {}, nil
+	case OutputFile:
+		file, err := os.Create(c.OutputPath())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("opening output: %w", err)
+		}
+		return file, file, func() { file.Close() }, nil
+	default:
+		return nil, nil, func() {}, nil
+	}
+}
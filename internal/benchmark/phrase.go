@@ -0,0 +1,380 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"chrono/internal/stats"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+type Checkpoint struct {
+	Name   string
+	Phrase string
+}
+
+type CheckpointTime struct {
+	Name    string
+	Elapsed time.Duration
+}
+
+// PhraseStream selects the output streams searched for the phrase.
+type PhraseStream int
+
+const (
+	PhraseStreamBoth PhraseStream = iota
+	PhraseStreamStdout
+	PhraseStreamStderr
+)
+
+// ParsePhraseStream parses the value of --phrase-stream.
+func ParsePhraseStream(value string) (PhraseStream, error) {
+	switch value {
+	case "both":
+		return PhraseStreamBoth, nil
+	case "stdout":
+		return PhraseStreamStdout, nil
+	case "stderr":
+		return PhraseStreamStderr, nil
+	default:
+		return 0, fmt.Errorf("expected stdout, stderr or both, got %q", value)
+	}
+}
+
+func (s PhraseStream) String() string {
+	switch s {
+	case PhraseStreamStdout:
+		return "stdout"
+	case PhraseStreamStderr:
+		return "stderr"
+	default:
+		return "both"
+	}
+}
+
+func (s PhraseStream) includes(kind LineKind) bool {
+	switch s {
+	case PhraseStreamStdout:
+		return kind == LineStdout
+	case PhraseStreamStderr:
+		return kind == LineStderr
+	default:
+		return true
+	}
+}
+
+// phraseMatch describes the line that ended a phrase-timed run, or that
+// contained a failure phrase.
+type phraseMatch struct {
+	elapsed     time.Duration
+	stream      LineKind
+	failure     string
+	text        string
+	captures    map[string]string
+	names       []string
+	checkpoints []CheckpointTime
+}
+
+// HasPhrase reports whether runs are timed until a phrase appears in the
+// output rather than until the command exits.
+func (c Config) HasPhrase() bool {
+	return c.Phrase != "" || c.PhraseRegex != nil || len(c.Checkpoints) > 0
+}
+
+// PhraseString describes the phrase for display, quoting plain phrases and
+// wrapping regular expressions in slashes.
+func (c Config) PhraseString() string {
+	description := c.phraseDescription()
+	if c.StartPhrase != "" {
+		description = fmt.Sprintf("%q → %s", c.StartPhrase, description)
+	}
+	if c.PhraseStream != PhraseStreamBoth {
+		description += " on " + c.PhraseStream.String()
+	}
+	return description
+}
+
+func (c Config) phraseDescription() string {
+	if len(c.Checkpoints) > 0 {
+		phrases := make([]string, len(c.Checkpoints))
+		for i, checkpoint := range c.Checkpoints {
+			phrases[i] = fmt.Sprintf("%s=%q", checkpoint.Name, checkpoint.Phrase)
+		}
+		return strings.Join(phrases, " → ")
+	}
+	if c.PhraseRegex != nil {
+		return "/" + c.PhraseRegex.String() + "/"
+	}
+	if c.IgnoreCase {
+		return fmt.Sprintf("%q (ignoring case)", c.Phrase)
+	}
+	return fmt.Sprintf("%q", c.Phrase)
+}
+
+func (c Config) containsPhrase(line, phrase string) bool {
+	if c.IgnoreCase {
+		return strings.Contains(strings.ToLower(line), strings.ToLower(phrase))
+	}
+	return strings.Contains(line, phrase)
+}
+
+// phraseEnd returns where the first occurrence of phrase in line ends, or -1.
+// Ignoring case, the line is compared a rune at a time rather than lowered,
+// so that the position is one in line itself.
+func (c Config) phraseEnd(line, phrase string) int {
+	if !c.IgnoreCase {
+		i := strings.Index(line, phrase)
+		if i < 0 {
+			return -1
+		}
+		return i + len(phrase)
+	}
+
+	for start := range line {
+		end := start
+		for _, want := range phrase {
+			r, size := utf8.DecodeRuneInString(line[end:])
+			if size == 0 || unicode.ToLower(r) != unicode.ToLower(want) {
+				end = -1
+				break
+			}
+			end += size
+		}
+		if end >= 0 {
+			return end
+		}
+	}
+	return -1
+}
+
+func (c Config) matchPhrase(line string) (phraseMatch, bool) {
+	if c.PhraseRegex == nil {
+		if !c.containsPhrase(line, c.Phrase) {
+			return phraseMatch{}, false
+		}
+		return phraseMatch{text: c.Phrase}, true
+	}
+
+	submatches := c.PhraseRegex.FindStringSubmatch(line)
+	if submatches == nil {
+		return phraseMatch{}, false
+	}
+
+	match := phraseMatch{text: submatches[0]}
+	for i, name := range c.PhraseRegex.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if match.captures == nil {
+			match.captures = make(map[string]string)
+		}
+		match.captures[name] = submatches[i]
+		match.names = append(match.names, name)
+	}
+	return match, true
+}
+
+func (m phraseMatch) String() string {
+	var s strings.Builder
+	s.WriteString("Match found!")
+	if m.text != "" {
+		fmt.Fprintf(&s, " %q", m.text)
+	}
+	if len(m.names) > 0 {
+		captures := make([]string, len(m.names))
+		for i, name := range m.names {
+			captures[i] = name + "=" + m.captures[name]
+		}
+		fmt.Fprintf(&s, " (%s)", strings.Join(captures, ", "))
+	}
+	fmt.Fprintf(&s, " on %s", m.stream)
+	return s.String()
+}
+
+// phraseMatcher looks for the phrase, or the sequence of checkpoints, and the
+// failure phrases in the output of a run. It is shared by the scanners of
+// stdout and stderr so that checkpoints are reached in order whichever stream
+// they appear on.
+type phraseMatcher struct {
+	config    Config
+	startTime time.Time
+
+	mu sync.Mutex
+	// phraseStart is when the phrase started being timed: the start of the
+	// run or, with a start phrase, when that was found.
+	phraseStart time.Time
+	started     bool
+	reached     []CheckpointTime
+	// matched is how far into the current line of each stream checkpoints
+	// have been found, so that a line matched before it was complete is not
+	// matched again from its start once the rest of it arrives.
+	matched map[LineKind]int
+}
+
+func newPhraseMatcher(config Config, startTime time.Time) *phraseMatcher {
+	return &phraseMatcher{config: config, startTime: startTime, phraseStart: startTime, started: config.StartPhrase == "", matched: make(map[LineKind]int)}
+}
+
+// endLine forgets how much of the current line of stream was matched, once
+// the next line starts.
+func (m *phraseMatcher) endLine(stream LineKind) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.matched, stream)
+}
+
+// dropLine accounts for the first n bytes of the current line of stream no
+// longer being matched.
+func (m *phraseMatcher) dropLine(stream LineKind, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matched[stream] = max(m.matched[stream]-n, 0)
+}
+
+// begin reports when the phrase started being timed, and whether it has. The
+// line that contains the start phrase starts it, and the marker for that is
+// returned.
+func (m *phraseMatcher) begin(line string, stream LineKind, at time.Time) (time.Time, bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started {
+		return m.phraseStart, true, ""
+	}
+	if !m.config.containsPhrase(line, m.config.StartPhrase) {
+		return time.Time{}, false, ""
+	}
+	m.started = true
+	m.phraseStart = at
+	return m.phraseStart, false, fmt.Sprintf("Start phrase found! %q on %s", m.config.StartPhrase, stream)
+}
+
+// match checks a line of output from stream, read at at. It returns the
+// markers to show for any checkpoints the line reached, and whether the run's
+// phrase is now complete or a failure phrase was found. Failure phrases are
+// looked for on every stream.
+func (m *phraseMatcher) match(line string, stream LineKind, at time.Time) ([]string, phraseMatch, bool) {
+	if m.config.PTY {
+		// Programs writing to a terminal colour their output, which must not
+		// stop a phrase from matching.
+		line = ansi.Strip(line)
+	}
+	if failure, ok := m.config.matchFailPhrase(line); ok {
+		match := phraseMatch{elapsed: at.Sub(m.startTime), stream: stream, failure: failure}
+		return []string{fmt.Sprintf("Failure phrase found! %s on %s", failure, stream)}, match, true
+	}
+	if !m.config.HasPhrase() || !m.config.PhraseStream.includes(stream) {
+		return nil, phraseMatch{}, false
+	}
+	phraseStart, started, marker := m.begin(line, stream, at)
+	if !started {
+		if marker == "" {
+			return nil, phraseMatch{}, false
+		}
+		return []string{marker}, phraseMatch{}, false
+	}
+	if len(m.config.Checkpoints) == 0 {
+		match, ok := m.config.matchPhrase(line)
+		if !ok {
+			return nil, phraseMatch{}, false
+		}
+		match.elapsed = at.Sub(phraseStart)
+		match.stream = stream
+		return []string{match.String()}, match, true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var markers []string
+	elapsed := at.Sub(phraseStart)
+	// Each checkpoint is looked for after the previous one's phrase, so that a
+	// single occurrence never reaches two checkpoints.
+	from := min(m.matched[stream], len(line))
+	for len(m.reached) < len(m.config.Checkpoints) {
+		checkpoint := m.config.Checkpoints[len(m.reached)]
+		end := m.config.phraseEnd(line[from:], checkpoint.Phrase)
+		if end < 0 {
+			break
+		}
+		from += end
+		m.reached = append(m.reached, CheckpointTime{Name: checkpoint.Name, Elapsed: elapsed})
+		markers = append(markers, fmt.Sprintf("Checkpoint reached: %s (%d/%d) on %s", checkpoint.Name, len(m.reached), len(m.config.Checkpoints), stream))
+	}
+	m.matched[stream] = from
+
+	if len(markers) == 0 || len(m.reached) < len(m.config.Checkpoints) {
+		return markers, phraseMatch{}, false
+	}
+	last := m.config.Checkpoints[len(m.config.Checkpoints)-1]
+	checkpoints := append([]CheckpointTime(nil), m.reached...)
+	return markers, phraseMatch{elapsed: elapsed, stream: stream, text: last.Phrase, checkpoints: checkpoints}, true
+}
+
+// checkpoints returns the checkpoints reached so far, with the shell overhead
+// subtracted from each.
+func (m *phraseMatcher) checkpoints(shellOverhead time.Duration) []CheckpointTime {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return adjustCheckpoints(m.reached, shellOverhead)
+}
+
+func adjustCheckpoints(checkpoints []CheckpointTime, shellOverhead time.Duration) []CheckpointTime {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+	adjusted := make([]CheckpointTime, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		adjusted[i] = CheckpointTime{Name: checkpoint.Name, Elapsed: max(checkpoint.Elapsed-shellOverhead, 0)}
+	}
+	return adjusted
+}
+
+type CheckpointSummary struct {
+	Name  string
+	Time  stats.Statistics
+	Split stats.Statistics
+}
+
+// CheckpointStatistics summarises, for every checkpoint, the time taken to
+// reach it and the split since the previous checkpoint across the counted
+// runs.
+func CheckpointStatistics(results []Result, config Config) []CheckpointSummary {
+	if len(config.Checkpoints) == 0 {
+		return nil
+	}
+
+	times := make([][]time.Duration, len(config.Checkpoints))
+	splits := make([][]time.Duration, len(config.Checkpoints))
+	for _, result := range results {
+		if !result.Found || len(result.Checkpoints) != len(config.Checkpoints) {
+			continue
+		}
+		var previous time.Duration
+		for i, checkpoint := range result.Checkpoints {
+			times[i] = append(times[i], checkpoint.Elapsed)
+			splits[i] = append(splits[i], checkpoint.Elapsed-previous)
+			previous = checkpoint.Elapsed
+		}
+	}
+	if len(times[0]) == 0 {
+		return nil
+	}
+
+	summaries := make([]CheckpointSummary, len(config.Checkpoints))
+	for i, checkpoint := range config.Checkpoints {
+		summaries[i] = CheckpointSummary{
+			Name:  checkpoint.Name,
+			Time:  stats.CalculateStatistics(times[i]),
+			Split: stats.CalculateStatistics(splits[i]),
+		}
+	}
+	return summaries
+}
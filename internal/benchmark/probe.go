@@ -0,0 +1,177 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// probeTimeout bounds a single connection attempt or HTTP request, so that a
+// probe that hangs is retried rather than holding up the run.
+const probeTimeout = time.Second
+
+// ProbeKind is the kind of readiness check that ends a run.
+type ProbeKind int
+
+const (
+	ProbeNone ProbeKind = iota
+	ProbeTCP
+	ProbeHTTP
+	ProbeFile
+	ProbeUnixSocket
+)
+
+// Probe is a readiness check polled while the command runs, as an
+// alternative to looking for a phrase in its output. The run ends as soon as
+// the check succeeds.
+type Probe struct {
+	Kind   ProbeKind
+	Target string
+	// Status is the HTTP status code a ProbeHTTP expects.
+	Status   int
+	Interval time.Duration
+}
+
+func (p Probe) String() string {
+	switch p.Kind {
+	case ProbeTCP:
+		return fmt.Sprintf("tcp %s", p.Target)
+	case ProbeHTTP:
+		return fmt.Sprintf("http %s (status %d)", p.Target, p.Status)
+	case ProbeFile:
+		return fmt.Sprintf("file %s", p.Target)
+	case ProbeUnixSocket:
+		return fmt.Sprintf("unix socket %s", p.Target)
+	default:
+		return "none"
+	}
+}
+
+// HasProbe reports whether runs are timed until a readiness probe succeeds.
+func (c Config) HasProbe() bool {
+	return c.Probe.Kind != ProbeNone
+}
+
+// existing returns the path a ProbeFile waits for as it is before the run
+// starts, or nil if there is nothing there yet.
+func (p Probe) existing() os.FileInfo {
+	if p.Kind != ProbeFile {
+		return nil
+	}
+	info, err := os.Stat(p.Target)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// check runs the probe once, returning why the target is not ready yet. A
+// ProbeFile whose path already existed, as existing, only succeeds once that
+// has been replaced or modified, so that a file left over from an earlier run
+// does not end the run straight away.
+func (p Probe) check(ctx context.Context, client *http.Client, existing os.FileInfo) error {
+	switch p.Kind {
+	case ProbeTCP, ProbeUnixSocket:
+		network := "tcp"
+		if p.Kind == ProbeUnixSocket {
+			network = "unix"
+		}
+		dialer := net.Dialer{Timeout: probeTimeout}
+		conn, err := dialer.DialContext(ctx, network, p.Target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case ProbeHTTP:
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Target, nil)
+		if err != nil {
+			return err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode != p.Status {
+			return fmt.Errorf("status %d, expected %d", response.StatusCode, p.Status)
+		}
+		return nil
+	case ProbeFile:
+		info, err := os.Stat(p.Target)
+		if err != nil {
+			return err
+		}
+		if existing != nil && os.SameFile(info, existing) && info.ModTime().Equal(existing.ModTime()) {
+			return fmt.Errorf("%s has not changed since the run started", p.Target)
+		}
+		return nil
+	default:
+		return errors.New("no probe configured")
+	}
+}
+
+// prober polls a probe in the background until it succeeds or is stopped.
+type prober struct {
+	stop context.CancelFunc
+	done chan struct{}
+	err  error
+}
+
+// startProbe polls config.Probe every interval from now on. When it succeeds
+// a marker is sent to lines and the time since startTime to found. existing
+// is what was at the probed path when the run started.
+func startProbe(config Config, existing os.FileInfo, startTime time.Time, found chan phraseMatch, lines chan<- Line) *prober {
+	ctx, stop := context.WithCancel(context.Background())
+	p := &prober{stop: stop, done: make(chan struct{})}
+
+	probe := config.Probe
+	// Connections are not kept alive, so every attempt and every run
+	// connects afresh.
+	client := &http.Client{Timeout: probeTimeout, Transport: &http.Transport{DisableKeepAlives: true}}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(probe.Interval)
+		defer ticker.Stop()
+
+		for {
+			err := probe.check(ctx, client, existing)
+			if err == nil {
+				match := phraseMatch{elapsed: time.Since(startTime)}
+				if sendLine(lines, Line{Kind: LineMatch, Text: fmt.Sprintf("Ready! %s", probe)}, ctx.Done()) {
+					select {
+					case found <- match:
+					default:
+					}
+				}
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			p.err = err
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// halt stops polling and waits for the current attempt to finish. It returns
+// why the last attempt failed.
+func (p *prober) halt() error {
+	if p == nil {
+		return nil
+	}
+	p.stop()
+	<-p.done
+	return p.err
+}
@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listenLater starts listening on network and address after delay, and stops
+// when the test ends.
+func listenLater(t *testing.T, network, address string, delay time.Duration) {
+	t.Helper()
+	timer := time.AfterFunc(delay, func() {
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			t.Errorf("Expected to listen on %s, got %v", address, err)
+			return
+		}
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+	})
+	t.Cleanup(func() { timer.Stop() })
+}
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected a free port, got %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestProbe(t *testing.T) {
+	var ready atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	tcpAddress := freeAddress(t)
+	socket := filepath.Join(dir, "server.sock")
+	file := filepath.Join(dir, "ready")
+
+	tests := []struct {
+		name    string
+		probe   Probe
+		command string
+		start   func()
+	}{
+		{"tcp", Probe{Kind: ProbeTCP, Target: tcpAddress}, "sleep 5", func() { listenLater(t, "tcp", tcpAddress, 100*time.Millisecond) }},
+		{"http", Probe{Kind: ProbeHTTP, Target: server.URL, Status: http.StatusNoContent}, "sleep 5", func() {
+			timer := time.AfterFunc(100*time.Millisecond, func() { ready.Store(true) })
+			t.Cleanup(func() { timer.Stop() })
+		}},
+		{"file", Probe{Kind: ProbeFile, Target: file}, "sleep 0.1; touch " + file + "; sleep 5", func() {}},
+		{"unix socket", Probe{Kind: ProbeUnixSocket, Target: socket}, "sleep 5", func() { listenLater(t, "unix", socket, 100*time.Millisecond) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.probe.Interval = 5 * time.Millisecond
+			config := Config{
+				Command: []string{"sh", "-c", tt.command},
+				Probe:   tt.probe,
+				Timeout: 5 * time.Second,
+			}
+			tt.start()
+
+			lines := make(chan Line, 10)
+			result, err := StreamContext(context.Background(), config, 0, lines)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !result.Found || result.Status != StatusSuccess {
+				t.Fatalf("Expected the probe to succeed, got %+v", result)
+			}
+			if result.Duration < 90*time.Millisecond || result.Duration > 2*time.Second {
+				t.Errorf("Expected the run to last until the probe succeeded, got %v", result.Duration)
+			}
+
+			var marker string
+			for line := range lines {
+				if line.Kind == LineMatch {
+					marker = line.Text
+				}
+			}
+			if marker != "Ready! "+tt.probe.String() {
+				t.Errorf("Unexpected ready marker %q", marker)
+			}
+		})
+	}
+}
+
+func TestProbeFileLeftOver(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ready")
+	config := Config{
+		Command: []string{"sh", "-c", "sleep 0.1; touch " + file + "; sleep 5"},
+		Probe:   Probe{Kind: ProbeFile, Target: file, Interval: 5 * time.Millisecond},
+		Timeout: 5 * time.Second,
+	}
+
+	// The file from the first run is still there when the second starts.
+	for run := 1; run <= 2; run++ {
+		result, err := RunContext(context.Background(), config, 0)
+		if err != nil || !result.Found {
+			t.Fatalf("Expected run %d to succeed, got %+v (%v)", run, result, err)
+		}
+		if result.Duration < 90*time.Millisecond {
+			t.Errorf("Expected run %d to last until the file was touched, got %v", run, result.Duration)
+		}
+	}
+}
+
+func TestProbeNotReady(t *testing.T) {
+	config := Config{
+		Command: []string{"sh", "-c", "sleep 0.05"},
+		Probe:   Probe{Kind: ProbeTCP, Target: freeAddress(t), Interval: 5 * time.Millisecond},
+		Timeout: 5 * time.Second,
+	}
+	result, err := RunContext(context.Background(), config, 0)
+	if err != nil {
+		t.Fatalf("Expected a failed probe not to be a run error, got %v", err)
+	}
+	if result.Found || result.Status != StatusNotReady {
+		t.Fatalf("Expected the run not to be ready, got %+v", result)
+	}
+	if reason := result.Reason(); !strings.HasPrefix(reason, "not ready before exit (code 0), last probe: ") {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+
+	config.Command = []string{"sleep", "5"}
+	config.Timeout = 100 * time.Millisecond
+	result, _ = RunContext(context.Background(), config, 0)
+	if result.Status != StatusTimeout {
+		t.Errorf("Expected the run to time out, got %+v", result)
+	}
+	if description := DescribeFailures([]Result{result}, config); description != "probe did not succeed before the timeout" {
+		t.Errorf("Unexpected failure description %q", description)
+	}
+}
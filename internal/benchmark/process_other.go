@@ -0,0 +1,56 @@
+//go:build !unix
+
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const processGroupExitTimeout = 0
+
+func configureProcess(cmd *exec.Cmd) {}
+
+func processExited(cmd *exec.Cmd) {}
+
+func killProcess(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
+
+func signalProcess(cmd *exec.Cmd, signal os.Signal) {
+	if cmd.Process != nil {
+		cmd.Process.Signal(signal)
+	}
+}
+
+func waitForProcessGroup(cmd *exec.Cmd, timeout time.Duration) bool {
+	return true
+}
+
+// ParseSignal accepts only SIGKILL and SIGINT, the signals that can be sent to
+// a process on this platform.
+func ParseSignal(name string) (os.Signal, error) {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "KILL", "9":
+		return os.Kill, nil
+	case "INT", "2":
+		return os.Interrupt, nil
+	}
+	return nil, fmt.Errorf("unknown signal %q", name)
+}
+
+func processSignal(state *os.ProcessState) string {
+	return ""
+}
+
+func processUsage(state *os.ProcessState) *Usage {
+	return &Usage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+}
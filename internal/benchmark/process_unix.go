@@ -0,0 +1,151 @@
+//go:build unix
+
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// processGroupExitTimeout bounds how long chrono waits for the rest of a
+// command's process group to exit after it has been killed.
+const processGroupExitTimeout = 5 * time.Second
+
+var subreaper sync.Once
+
+// configureProcess starts the command in its own process group so that it can
+// be killed along with every process it starts.
+func configureProcess(cmd *exec.Cmd) {
+	subreaper.Do(becomeSubreaper)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// processExited hands whatever is left of the command's process group to the
+// reaper once the command itself has been waited for.
+func processExited(cmd *exec.Cmd) {
+	if cmd.Process != nil && cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid {
+		releaseProcessGroup(cmd.Process.Pid)
+	}
+}
+
+func killProcess(cmd *exec.Cmd) {
+	signalProcess(cmd, syscall.SIGKILL)
+}
+
+// signalProcess sends signal to every process in the command's group, falling
+// back to the command itself when it has no group of its own.
+func signalProcess(cmd *exec.Cmd, signal os.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	sig, ok := signal.(syscall.Signal)
+	if !ok || syscall.Kill(-cmd.Process.Pid, sig) != nil {
+		cmd.Process.Signal(signal)
+	}
+}
+
+// waitForProcessGroup waits up to timeout for every member of the command's
+// process group to exit, so that none of them outlive the run. It reports
+// whether they all did.
+func waitForProcessGroup(cmd *exec.Cmd, timeout time.Duration) bool {
+	if cmd.Process == nil || cmd.SysProcAttr == nil || !(cmd.SysProcAttr.Setpgid || cmd.SysProcAttr.Setsid) {
+		return true
+	}
+
+	pgid := cmd.Process.Pid
+	deadline := time.Now().Add(timeout)
+	for {
+		reapProcessGroup(pgid)
+		if err := syscall.Kill(-pgid, 0); errors.Is(err, syscall.ESRCH) {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func processSignal(state *os.ProcessState) string {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	return status.Signal().String()
+}
+
+func processUsage(state *os.ProcessState) *Usage {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return nil
+	}
+
+	// ru_maxrss is reported in bytes on macOS and in kilobytes elsewhere.
+	maxRSS := int64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+
+	return &Usage{
+		UserTime:            time.Duration(rusage.Utime.Nano()),
+		SystemTime:          time.Duration(rusage.Stime.Nano()),
+		MaxRSS:              maxRSS,
+		MinorFaults:         int64(rusage.Minflt),
+		MajorFaults:         int64(rusage.Majflt),
+		VoluntarySwitches:   int64(rusage.Nvcsw),
+		InvoluntarySwitches: int64(rusage.Nivcsw),
+	}
+}
+
+// reapProcessGroup collects members of the group that have exited and were
+// reparented to chrono, which would otherwise remain as zombies.
+func reapProcessGroup(pgid int) {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-pgid, &status, syscall.WNOHANG, nil)
+		if err != nil || pid <= 0 {
+			return
+		}
+	}
+}
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// maxSignal is the highest signal number that ParseSignal accepts: SIGRTMAX on
+// Linux, and 31 elsewhere, as on macOS.
+func maxSignal() int {
+	if runtime.GOOS == "linux" {
+		return 64
+	}
+	return 31
+}
+
+// ParseSignal parses a signal name such as SIGTERM or TERM, or a signal number.
+func ParseSignal(name string) (os.Signal, error) {
+	if number, err := strconv.Atoi(name); err == nil {
+		if number < 1 || number > maxSignal() {
+			return nil, fmt.Errorf("signal %d is out of range (1-%d)", number, maxSignal())
+		}
+		return syscall.Signal(number), nil
+	}
+	if sig, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]; ok {
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unknown signal %q", name)
+}
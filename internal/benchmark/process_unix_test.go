@@ -0,0 +1,114 @@
+//go:build unix
+
+package benchmark
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProcessGroupKilled(t *testing.T) {
+	for _, phrase := range []string{"", "ready"} {
+		t.Run("phrase "+strconv.Quote(phrase), func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "pid")
+			script := "sleep 30 & echo $! > " + pidFile + "; echo ready; wait"
+			config := Config{
+				Command: []string{"sh", "-c", script},
+				Phrase:  phrase,
+				Timeout: 500 * time.Millisecond,
+			}
+
+			start := time.Now()
+			Run(config, 0)
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("Expected the process group to exit promptly, took %v", elapsed)
+			}
+
+			data, err := os.ReadFile(pidFile)
+			if err != nil {
+				t.Fatalf("Failed to read background pid: %v", err)
+			}
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatalf("Invalid background pid %q: %v", data, err)
+			}
+			if err := syscall.Kill(pid, 0); !errors.Is(err, syscall.ESRCH) {
+				syscall.Kill(pid, syscall.SIGKILL)
+				t.Errorf("Expected background process %d to be killed, got %v", pid, err)
+			}
+		})
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	t.Run("signal handled", func(t *testing.T) {
+		config := Config{
+			Command:    []string{"sh", "-c", `trap "sleep 0.2; exit 0" TERM; echo ready; while :; do sleep 0.05; done`},
+			Phrase:     "ready",
+			KillSignal: syscall.SIGTERM,
+			KillGrace:  5 * time.Second,
+		}
+		result := Run(config, 0)
+
+		if !result.Found {
+			t.Fatalf("Expected phrase to be found, got %+v", result)
+		}
+		if result.Shutdown < 200*time.Millisecond || result.Shutdown > 2*time.Second {
+			t.Errorf("Expected shutdown to include the trap's delay, got %v", result.Shutdown)
+		}
+		if result.Signal != "" || result.ExitCode != 0 {
+			t.Errorf("Expected a clean exit from the trap, got exit %d signal %q", result.ExitCode, result.Signal)
+		}
+	})
+
+	t.Run("escalates after grace period", func(t *testing.T) {
+		config := Config{
+			Command:    []string{"sh", "-c", `trap "" TERM; echo ready; sleep 10`},
+			Phrase:     "ready",
+			KillSignal: syscall.SIGTERM,
+			KillGrace:  200 * time.Millisecond,
+		}
+		result := Run(config, 0)
+
+		if result.Shutdown < 200*time.Millisecond || result.Shutdown > 2*time.Second {
+			t.Errorf("Expected shutdown to last about the grace period, got %v", result.Shutdown)
+		}
+		if result.Signal != syscall.SIGKILL.String() {
+			t.Errorf("Expected the command to be killed, got signal %q", result.Signal)
+		}
+	})
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected syscall.Signal
+	}{
+		{"SIGTERM", syscall.SIGTERM},
+		{"term", syscall.SIGTERM},
+		{"INT", syscall.SIGINT},
+		{"9", syscall.SIGKILL},
+	}
+	for _, test := range tests {
+		sig, err := ParseSignal(test.name)
+		if err != nil {
+			t.Errorf("ParseSignal(%q) returned error: %v", test.name, err)
+			continue
+		}
+		if sig != test.expected {
+			t.Errorf("ParseSignal(%q) = %v, expected %v", test.name, sig, test.expected)
+		}
+	}
+
+	for _, name := range []string{"SIGNOPE", "0", "-9", "999"} {
+		if _, err := ParseSignal(name); err == nil {
+			t.Errorf("Expected an error for signal %q", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+type windowSize struct {
+	rows, columns, xPixels, yPixels uint16
+}
+
+// openPTY opens a pseudo-terminal with the given window size and returns its
+// master end, which chrono reads, and its slave end, which the command writes.
+func openPTY(columns, rows int) (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening pseudo-terminal: %w", err)
+	}
+
+	var number uint32
+	unlock := int32(0)
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlocking pseudo-terminal: %w", err)
+	}
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("finding pseudo-terminal: %w", err)
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", number), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("opening pseudo-terminal: %w", err)
+	}
+
+	size := windowSize{rows: uint16(rows), columns: uint16(columns)}
+	if err := ioctl(master, syscall.TIOCSWINSZ, unsafe.Pointer(&size)); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, fmt.Errorf("setting pseudo-terminal size: %w", err)
+	}
+	return master, slave, nil
+}
+
+// ioctl runs an ioctl on file without taking its descriptor out of the
+// runtime's poller, as File.Fd would, so that closing it still interrupts
+// reads.
+func ioctl(file *os.File, request uintptr, arg unsafe.Pointer) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// useControllingTerminal starts the command in a session of its own with the
+// pseudo-terminal on its stdout as the controlling terminal. The session is
+// also the command's process group, so it is still killed as a whole.
+func useControllingTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 1}
+}
@@ -0,0 +1,58 @@
+package benchmark
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPTY(t *testing.T) {
+	if master, slave, err := openPTY(80, 24); err != nil {
+		t.Skipf("Pseudo-terminals are not available: %v", err)
+	} else {
+		master.Close()
+		slave.Close()
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		phrase string
+	}{
+		{"stdout is a terminal", "test -t 1 && test -t 2 && echo is-a-tty", "is-a-tty"},
+		{"window size", "stty size <&1", "30 100"},
+		{"stderr", "echo from-stderr >&2", "from-stderr"},
+		{"colours are ignored when matching", `printf 'server \033[32mready\033[0m\n'; sleep 5`, "server ready"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{
+				Command:    []string{test.script},
+				Script:     true,
+				Shell:      "/bin/sh",
+				Phrase:     test.phrase,
+				PTY:        true,
+				PTYColumns: 100,
+				PTYRows:    30,
+				Timeout:    3 * time.Second,
+			}
+			result, err := RunContext(context.Background(), config, 0)
+			if err != nil || !result.Found {
+				t.Errorf("Expected %q to be found, got %+v (%v)", test.phrase, result, err)
+			}
+		})
+	}
+
+	t.Run("command completion", func(t *testing.T) {
+		lines := make(chan Line, 10)
+		config := Config{Command: []string{"echo", "done"}, PTY: true, PTYColumns: 80, PTYRows: 24}
+		result, err := StreamContext(context.Background(), config, 0, lines)
+		if err != nil || !result.Found {
+			t.Fatalf("Expected success, got %+v (%v)", result, err)
+		}
+		if line := <-lines; line.Text != "done" {
+			t.Errorf("Expected the output of the pseudo-terminal, got %q", line.Text)
+		}
+	})
+}
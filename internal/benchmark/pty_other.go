@@ -0,0 +1,15 @@
+//go:build !linux
+
+package benchmark
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func openPTY(columns, rows int) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("--pty is only supported on Linux")
+}
+
+func useControllingTerminal(cmd *exec.Cmd) {}
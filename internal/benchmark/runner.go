@@ -1,156 +1,629 @@
 package benchmark
 
 import (
-	"bufio"
-	"io"
-	"log"
+	"context"
+	"fmt"
+	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"time"
 )
 
 type Config struct {
+	StartPhrase     string
 	Phrase          string
+	PhraseRegex     *regexp.Regexp
+	IgnoreCase      bool
+	PhraseStream    PhraseStream
+	FailPhrases     []string
+	FailRegexes     []*regexp.Regexp
+	Checkpoints     []Checkpoint
+	Probe           Probe
 	Warmups         int
 	Runs            int
+	AutoRuns        bool
+	MinRuns         int
+	MaxRuns         int
+	TargetRSE       float64
+	MaxTime         time.Duration
+	Concurrency     int
+	LoadDuration    time.Duration
 	Timeout         time.Duration
+	IdleTimeout     time.Duration
+	UntilQuiet      time.Duration
 	CalibrationRuns int
 	SkipCalibration bool
 	Command         []string
+	Script          bool
+	Shell           string
+	Env             []string
+	ClearEnv        bool
+	Dir             string
+	Stdin           StdinMode
+	Input           string
+	PTY             bool
+	PTYColumns      int
+	PTYRows         int
+	Output          OutputMode
+	OutputFile      string
+	RunLabel        string
 	UseCli          bool
+	ParameterName   string
+	ParameterValue  string
+	Setup           string
+	Prepare         string
+	Cleanup         string
+	Conclude        string
+	IgnoreFailure   bool
+	KillSignal      os.Signal
+	KillGrace       time.Duration
 }
 
-type Result struct {
-	Duration time.Duration
-	Found    bool
+func (c Config) CommandString() string {
+	if c.isScript() {
+		return c.Command[0]
+	}
+	args := make([]string, len(c.Command))
+	for i, arg := range c.Command {
+		args[i] = quoteArg(arg)
+	}
+	return strings.Join(args, " ")
 }
 
-func Run(config Config, shellOverhead time.Duration) Result {
-	cmd := exec.Command(config.Command[0], config.Command[1:]...)
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+type Status int
 
-	if config.Phrase == "" {
-		return runCommandCompletion(cmd, config.Timeout, shellOverhead)
+const (
+	StatusSuccess Status = iota
+	StatusTimeout
+	StatusStalled
+	StatusPhraseNotFound
+	StatusFailPhrase
+	StatusNotReady
+	StatusNonZeroExit
+	StatusStartFailure
+	StatusCancelled
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusSuccess:
+		return "success"
+	case StatusTimeout:
+		return "timed out"
+	case StatusStalled:
+		return "stalled"
+	case StatusPhraseNotFound:
+		return "phrase not found"
+	case StatusFailPhrase:
+		return "failure phrase found"
+	case StatusNotReady:
+		return "not ready"
+	case StatusNonZeroExit:
+		return "non-zero exit"
+	case StatusStartFailure:
+		return "failed to start"
+	case StatusCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
 	}
+}
 
-	return runPhraseDetection(cmd, config.Phrase, config.Timeout, shellOverhead)
+type Result struct {
+	Duration    time.Duration
+	Found       bool
+	Status      Status
+	ExitCode    int
+	Signal      string
+	Err         error
+	Usage       *Usage
+	Shutdown    time.Duration
+	StalledAt   time.Duration
+	FirstOutput map[LineKind]time.Duration
+	Match       string
+	MatchStream LineKind
+	FailPhrase  string
+	Captures    map[string]string
+	Checkpoints []CheckpointTime
 }
 
-func runCommandCompletion(cmd *exec.Cmd, timeout time.Duration, shellOverhead time.Duration) Result {
-	startTime := time.Now()
+func (r Result) exited() bool {
+	return r.ExitCode >= 0 && r.Signal == ""
+}
 
-	if err := cmd.Start(); err != nil {
-		log.Fatal("Error starting command:", err)
+// Reason describes why a run failed, or how a counted run ended when it did
+// not exit cleanly. It is empty for ordinary successful runs.
+func (r Result) Reason() string {
+	switch r.Status {
+	case StatusSuccess:
+		return ""
+	case StatusNonZeroExit:
+		if r.Signal != "" {
+			return fmt.Sprintf("terminated by signal: %s", r.Signal)
+		}
+		if r.ExitCode == 127 {
+			return "exited with code 127 (command not found)"
+		}
+		return fmt.Sprintf("exited with code %d", r.ExitCode)
+	case StatusPhraseNotFound:
+		if r.Err != nil {
+			return fmt.Sprintf("phrase not found: %v", r.Err)
+		}
+		if r.exited() {
+			return fmt.Sprintf("phrase not found before exit (code %d)", r.ExitCode)
+		}
+		if r.Signal != "" {
+			return fmt.Sprintf("phrase not found before signal: %s", r.Signal)
+		}
+		return "phrase not found"
+	case StatusFailPhrase:
+		return fmt.Sprintf("failure phrase %s found on %s", r.FailPhrase, r.MatchStream)
+	case StatusStalled:
+		return fmt.Sprintf("stalled: no output after %s", r.StalledAt.Round(time.Millisecond))
+	case StatusNotReady:
+		if r.exited() {
+			return fmt.Sprintf("not ready before exit (code %d)%s", r.ExitCode, probeErr(r.Err))
+		}
+		if r.Signal != "" {
+			return fmt.Sprintf("not ready before signal: %s%s", r.Signal, probeErr(r.Err))
+		}
+		return "not ready" + probeErr(r.Err)
+	case StatusStartFailure:
+		if r.Err != nil {
+			return fmt.Sprintf("failed to start: %v", r.Err)
+		}
+		return "failed to start"
+	default:
+		return r.Status.String()
+	}
+}
+
+func probeErr(err error) string {
+	if err == nil {
+		return ""
 	}
+	return fmt.Sprintf(", last probe: %v", err)
+}
 
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
+// DescribeFailures summarises why the failed runs in results did not count.
+func DescribeFailures(results []Result, config Config) string {
+	if len(results) == 0 {
+		return "no runs completed"
+	}
 
-	if timeout > 0 {
-		select {
-		case <-done:
-			duration := time.Since(startTime)
-			adjustedDuration := max(duration-shellOverhead, 0)
-			return Result{Duration: adjustedDuration, Found: true}
-		case <-time.After(timeout):
-			killProcess(cmd)
-			return Result{Found: false}
+	statuses := make(map[Status]bool)
+	for _, result := range results {
+		if !result.Found {
+			statuses[result.Status] = true
+		}
+	}
+	if len(statuses) != 1 {
+		return "all runs failed: " + FormatFailureCauses(FailureCauses(results))
+	}
+
+	for status := range statuses {
+		switch status {
+		case StatusTimeout:
+			if config.HasProbe() {
+				return "probe did not succeed before the timeout"
+			}
+			if !config.HasPhrase() {
+				return "all commands timed out"
+			}
+			return "phrase was not found before the timeout"
+		case StatusStalled:
+			return "every run stalled without output for the idle timeout"
+		case StatusPhraseNotFound:
+			return "phrase was not found in any execution"
+		case StatusNotReady:
+			return "probe did not succeed in any execution"
+		case StatusFailPhrase:
+			return "a failure phrase was found in every execution: " + FormatFailureCauses(FailureCauses(results))
+		case StatusNonZeroExit:
+			return "all commands exited with a non-zero code (use --ignore-failure to count them)"
+		case StatusStartFailure:
+			return "command failed to start"
 		}
-	} else {
-		<-done
-		duration := time.Since(startTime)
-		adjustedDuration := max(duration-shellOverhead, 0)
-		return Result{Duration: adjustedDuration, Found: true}
 	}
+	return "all runs failed"
+}
+
+type LineKind int
+
+const (
+	LineStdout LineKind = iota
+	LineStderr
+	// LineMatch is a marker added by chrono, such as when the phrase is found.
+	LineMatch
+	// LineFailure is a marker for a run that failed, such as when it stalls.
+	LineFailure
+)
+
+type Line struct {
+	Kind LineKind
+	Text string
+}
+
+// Run is RunContext without cancellation, for callers that only need the
+// result. Errors are still recorded in Result.Err.
+func Run(config Config, shellOverhead time.Duration) Result {
+	result, _ := RunContext(context.Background(), config, shellOverhead)
+	return result
+}
+
+// RunContext runs the benchmark once. Start failures, pipe errors on runs that
+// did not count and cancellation of ctx are returned alongside a result
+// describing the run.
+func RunContext(ctx context.Context, config Config, shellOverhead time.Duration) (Result, error) {
+	return StreamContext(ctx, config, shellOverhead, nil)
 }
 
-func runPhraseDetection(cmd *exec.Cmd, phrase string, timeout time.Duration, shellOverhead time.Duration) Result {
-	stdout, err := cmd.StdoutPipe()
+// StreamContext runs the benchmark like RunContext while sending every line of
+// output, and a LineMatch marker when the phrase is found, to lines. lines is
+// closed once the run has finished.
+func StreamContext(ctx context.Context, config Config, shellOverhead time.Duration, lines chan<- Line) (Result, error) {
+	if lines != nil {
+		defer close(lines)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return cancelledResult(nil), err
+	}
+
+	cmd := config.command()
+	configureProcess(cmd)
+
+	stdin, closeStdin, err := config.openStdin()
 	if err != nil {
-		log.Fatal("Error creating stdout pipe:", err)
+		result := startFailure(err)
+		return result, result.Err
 	}
+	defer closeStdin()
+	cmd.Stdin = stdin
 
-	stderr, err := cmd.StderrPipe()
+	stdout, stderr, closeOutput, err := config.openOutput()
 	if err != nil {
-		log.Fatal("Error creating stderr pipe:", err)
+		result := startFailure(err)
+		return result, result.Err
+	}
+	defer closeOutput()
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	var result Result
+	if !config.stopsEarly() {
+		result = runCommandCompletion(ctx, cmd, config, shellOverhead, lines)
+	} else {
+		result = runPhraseDetection(ctx, cmd, config, shellOverhead, lines)
+	}
+
+	// An interrupt from the terminal can reach the command before the
+	// cancellation is noticed, so any run that overlaps it is cancelled.
+	if err := ctx.Err(); err != nil {
+		return cancelledResult(cmd), err
+	}
+	if result.Found || result.Status == StatusNotReady {
+		// Output that could not be read to the end does not stop a run from
+		// counting, and the last probe error only explains why the run
+		// failed, so both are left on the result.
+		return result, nil
+	}
+	return result, result.Err
+}
+
+// stopsEarly reports whether runs end on a condition other than the command
+// exiting: a phrase, a readiness probe or the output going quiet.
+func (c Config) stopsEarly() bool {
+	return c.HasPhrase() || c.HasProbe() || c.UntilQuiet > 0
+}
+
+func runCommandCompletion(ctx context.Context, cmd *exec.Cmd, config Config, shellOverhead time.Duration, lines chan<- Line) Result {
+	var streams *outputStreams
+	if config.needsPipe() {
+		var err error
+		if streams, err = config.attachOutput(cmd); err != nil {
+			return startFailure(err)
+		}
 	}
 
 	startTime := time.Now()
 
 	if err := cmd.Start(); err != nil {
-		log.Fatal("Error starting command:", err)
+		streams.abort()
+		return startFailure(err)
 	}
+	streams.closeChildEnds()
 
-	done := make(chan time.Duration, 1)
-	cancel := make(chan struct{})
-	cmdFinished := make(chan struct{})
-
-	go scanOutput(stdout, phrase, startTime, done, cancel)
-	go scanOutput(stderr, phrase, startTime, done, cancel)
+	// Without a phrase the output is only matched for failure phrases.
+	var matcher *phraseMatcher
+	if config.HasFailPhrases() {
+		matcher = newPhraseMatcher(config, startTime)
+	}
+	found := make(chan phraseMatch, 1)
+	streams.scan(matcher, found, lines)
+	stalledC := streams.stalled(startTime, config.IdleTimeout)
 
+	cmdFinished := make(chan struct{})
 	go func() {
 		cmd.Wait()
+		processExited(cmd)
 		close(cmdFinished)
 	}()
 
-	if timeout > 0 {
-		select {
-		case duration := <-done:
-			close(cancel)
-			killProcess(cmd)
-			adjustedDuration := max(duration-shellOverhead, 0)
-			return Result{Duration: adjustedDuration, Found: true}
-		case <-time.After(timeout):
-			close(cancel)
-			killProcess(cmd)
-			return Result{Found: false}
-		case <-cmdFinished:
-			close(cancel)
-			return Result{Found: false}
-		}
-	} else {
+	var timeoutC <-chan time.Time
+	if config.Timeout > 0 {
+		timer := time.NewTimer(config.Timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	var result Result
+	select {
+	case <-cmdFinished:
+		duration := time.Since(startTime)
+		streams.drain()
 		select {
-		case duration := <-done:
-			close(cancel)
-			killProcess(cmd)
-			adjustedDuration := max(duration-shellOverhead, 0)
-			return Result{Duration: adjustedDuration, Found: true}
-		case <-cmdFinished:
-			close(cancel)
-			return Result{Found: false}
+		case match := <-found:
+			result = phraseResult(cmd, match, shellOverhead, 0)
+		default:
+			result = exitResult(cmd, config, max(duration-shellOverhead, 0))
+			result.Err = streams.readErr()
 		}
+	case match := <-found:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = phraseResult(cmd, match, shellOverhead, shutdown)
+	case <-timeoutC:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = timeoutResult(cmd, shutdown)
+	case last := <-stalledC:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = stalledResult(ctx, cmd, config, last.Sub(startTime)-shellOverhead, shutdown, lines)
+	case <-ctx.Done():
+		stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		return cancelledResult(cmd)
 	}
+	result.FirstOutput = streams.firstOutput(startTime, shellOverhead)
+	return result
 }
 
-func scanOutput(reader io.Reader, phrase string, startTime time.Time, done chan time.Duration, cancel chan struct{}) {
-	defer func() {
-		if closer, ok := reader.(io.Closer); ok {
-			closer.Close()
+// runPhraseDetection times the command until the phrase is found in its
+// output, until a readiness probe succeeds or, with UntilQuiet, until the last
+// output before it went quiet.
+func runPhraseDetection(ctx context.Context, cmd *exec.Cmd, config Config, shellOverhead time.Duration, lines chan<- Line) Result {
+	var streams *outputStreams
+	if config.needsPipe() {
+		var err error
+		if streams, err = config.attachOutput(cmd); err != nil {
+			return startFailure(err)
 		}
+	}
+
+	existing := config.Probe.existing()
+	startTime := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		streams.abort()
+		return startFailure(err)
+	}
+	streams.closeChildEnds()
+
+	// Timing from a start phrase leaves out the cost of spawning the
+	// command, so the shell overhead is not subtracted from the phrase.
+	phraseOverhead := shellOverhead
+	if config.StartPhrase != "" {
+		phraseOverhead = 0
+	}
+
+	var matcher *phraseMatcher
+	if config.HasPhrase() || config.HasFailPhrases() {
+		matcher = newPhraseMatcher(config, startTime)
+	}
+	found := make(chan phraseMatch, 1)
+	streams.scan(matcher, found, lines)
+	stalledC := streams.stalled(startTime, config.IdleTimeout)
+	quietC := streams.quiet(config.UntilQuiet)
+
+	var probe *prober
+	if config.HasProbe() {
+		probe = startProbe(config, existing, startTime, found, lines)
+	}
+	defer probe.halt()
+
+	cmdFinished := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		processExited(cmd)
+		close(cmdFinished)
 	}()
 
-	scanner := bufio.NewScanner(reader)
+	var timeoutC <-chan time.Time
+	if config.Timeout > 0 {
+		timer := time.NewTimer(config.Timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
 
-	for scanner.Scan() {
+	var result Result
+	select {
+	case match := <-found:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = phraseResult(cmd, match, phraseOverhead, shutdown)
+	case <-timeoutC:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = timeoutResult(cmd, shutdown)
+		result.Checkpoints = matcher.checkpoints(phraseOverhead)
+	case last := <-stalledC:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = stalledResult(ctx, cmd, config, last.Sub(startTime)-shellOverhead, shutdown, lines)
+		result.Checkpoints = matcher.checkpoints(phraseOverhead)
+	case last := <-quietC:
+		shutdown := stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		result = quietResult(ctx, cmd, config, last.Sub(startTime)-shellOverhead, shutdown, lines)
+	case <-ctx.Done():
+		stopProcess(cmd, config, cmdFinished)
+		streams.close()
+		return cancelledResult(cmd)
+	case <-cmdFinished:
+		duration := time.Since(startTime)
+		lastProbeErr := probe.halt()
+		streams.drain()
 		select {
-		case <-cancel:
-			return
+		case match := <-found:
+			result = phraseResult(cmd, match, phraseOverhead, 0)
 		default:
-		}
-		line := scanner.Text()
-		if strings.Contains(line, phrase) {
-			select {
-			case done <- time.Since(startTime):
-			default:
+			if config.UntilQuiet > 0 {
+				// Output stops for good when the command exits, so the
+				// run is timed to its last output like a quiet one.
+				if last, ok := streams.lastOutputTime(); ok {
+					duration = last.Sub(startTime)
+				}
+				result = exitResult(cmd, config, max(duration-shellOverhead, 0))
+				break
+			}
+			result = processResult(cmd)
+			if config.HasProbe() {
+				result.Status = StatusNotReady
+				result.Err = lastProbeErr
+			} else {
+				result.Status = StatusPhraseNotFound
+				result.Err = streams.readErr()
+				result.Checkpoints = matcher.checkpoints(phraseOverhead)
 			}
-			return
 		}
 	}
+	result.FirstOutput = streams.firstOutput(startTime, shellOverhead)
+	return result
+}
+
+func processResult(cmd *exec.Cmd) Result {
+	result := Result{ExitCode: -1}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		result.Signal = processSignal(cmd.ProcessState)
+		result.Usage = processUsage(cmd.ProcessState)
+	}
+	return result
+}
+
+func exitResult(cmd *exec.Cmd, config Config, duration time.Duration) Result {
+	result := processResult(cmd)
+	result.Duration = duration
+
+	if result.ExitCode == 0 {
+		result.Status = StatusSuccess
+		result.Found = true
+	} else {
+		result.Status = StatusNonZeroExit
+		result.Found = config.IgnoreFailure
+	}
+	return result
 }
 
-func killProcess(cmd *exec.Cmd) {
-	if cmd.Process != nil {
-		cmd.Process.Kill()
+// phraseResult describes a run that ended on a line of its output: the phrase
+// or a failure phrase.
+func phraseResult(cmd *exec.Cmd, match phraseMatch, shellOverhead, shutdown time.Duration) Result {
+	result := processResult(cmd)
+	result.Duration = max(match.elapsed-shellOverhead, 0)
+	result.Shutdown = shutdown
+	if match.failure != "" {
+		result.MatchStream = match.stream
+		result.FailPhrase = match.failure
+		result.Status = StatusFailPhrase
+		return result
 	}
+	result.Match = match.text
+	result.MatchStream = match.stream
+	result.Captures = match.captures
+	result.Checkpoints = adjustCheckpoints(match.checkpoints, shellOverhead)
+	result.Status = StatusSuccess
+	result.Found = true
+	return result
+}
+
+func timeoutResult(cmd *exec.Cmd, shutdown time.Duration) Result {
+	result := processResult(cmd)
+	result.Shutdown = shutdown
+	result.Status = StatusTimeout
+	return result
+}
+
+// stalledResult describes a run stopped because it wrote nothing for the idle
+// timeout after stalledAt, and marks the stall in the output.
+func stalledResult(ctx context.Context, cmd *exec.Cmd, config Config, stalledAt, shutdown time.Duration, lines chan<- Line) Result {
+	result := processResult(cmd)
+	result.Shutdown = shutdown
+	result.StalledAt = max(stalledAt, 0)
+	result.Status = StatusStalled
+	sendLine(lines, Line{Kind: LineFailure, Text: fmt.Sprintf("Stalled! No output for %s after %s", config.IdleTimeout, result.StalledAt.Round(time.Millisecond))}, ctx.Done())
+	return result
+}
+
+// quietResult describes a run that ended when its output went quiet after
+// quietAt, and marks that point in the output.
+func quietResult(ctx context.Context, cmd *exec.Cmd, config Config, quietAt, shutdown time.Duration, lines chan<- Line) Result {
+	result := processResult(cmd)
+	result.Duration = max(quietAt, 0)
+	result.Shutdown = shutdown
+	result.Status = StatusSuccess
+	result.Found = true
+	sendLine(lines, Line{Kind: LineMatch, Text: fmt.Sprintf("Quiet! No output for %s after %s", config.UntilQuiet, result.Duration.Round(time.Millisecond))}, ctx.Done())
+	return result
+}
+
+func cancelledResult(cmd *exec.Cmd) Result {
+	result := Result{ExitCode: -1}
+	if cmd != nil {
+		result = processResult(cmd)
+	}
+	result.Status = StatusCancelled
+	return result
+}
+
+func startFailure(err error) Result {
+	return Result{Status: StatusStartFailure, ExitCode: -1, Err: err}
+}
+
+// stopProcess stops the command and every process it started, and reports how
+// long they took to exit. When a kill signal other than SIGKILL is configured
+// it is sent first, escalating to SIGKILL once the grace period has passed.
+func stopProcess(cmd *exec.Cmd, config Config, finished <-chan struct{}) time.Duration {
+	start := time.Now()
+
+	if config.KillSignal != nil && config.KillSignal != os.Kill {
+		signalProcess(cmd, config.KillSignal)
+
+		grace := time.NewTimer(config.KillGrace)
+		defer grace.Stop()
+
+		select {
+		case <-finished:
+			if waitForProcessGroup(cmd, time.Until(start.Add(config.KillGrace))) {
+				return time.Since(start)
+			}
+		case <-grace.C:
+		}
+	}
+
+	killProcess(cmd)
+	<-finished
+	waitForProcessGroup(cmd, processGroupExitTimeout)
+	return time.Since(start)
 }
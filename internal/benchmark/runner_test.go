@@ -1,7 +1,13 @@
 package benchmark
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -140,16 +146,16 @@ func TestKillProcess(t *testing.T) {
 func TestScanOutput(t *testing.T) {
 	t.Run("phrase found", func(t *testing.T) {
 		reader := strings.NewReader("line1\nphrase here\nline3")
-		done := make(chan time.Duration, 1)
+		done := make(chan phraseMatch, 1)
 		cancel := make(chan struct{})
 		startTime := time.Now()
 
-		go scanOutput(reader, "phrase", startTime, done, cancel)
+		go scanOutput(reader, LineStdout, nil, newPhraseMatcher(Config{Phrase: "phrase"}, startTime), done, cancel, nil)
 
 		select {
-		case duration := <-done:
-			if duration <= 0 {
-				t.Errorf("Expected positive duration, got %v", duration)
+		case match := <-done:
+			if match.elapsed <= 0 {
+				t.Errorf("Expected positive duration, got %v", match.elapsed)
 			}
 		case <-time.After(1 * time.Second):
 			t.Errorf("Expected phrase to be found quickly")
@@ -158,11 +164,11 @@ func TestScanOutput(t *testing.T) {
 
 	t.Run("phrase not found", func(t *testing.T) {
 		reader := strings.NewReader("line1\nline2\nline3")
-		done := make(chan time.Duration, 1)
+		done := make(chan phraseMatch, 1)
 		cancel := make(chan struct{})
 		startTime := time.Now()
 
-		go scanOutput(reader, "notfound", startTime, done, cancel)
+		go scanOutput(reader, LineStdout, nil, newPhraseMatcher(Config{Phrase: "notfound"}, startTime), done, cancel, nil)
 
 		select {
 		case <-done:
@@ -173,12 +179,12 @@ func TestScanOutput(t *testing.T) {
 
 	t.Run("cancelled", func(t *testing.T) {
 		reader := strings.NewReader("line1\nphrase here\nline3")
-		done := make(chan time.Duration, 1)
+		done := make(chan phraseMatch, 1)
 		cancel := make(chan struct{})
 		startTime := time.Now()
 
 		close(cancel)
-		go scanOutput(reader, "phrase", startTime, done, cancel)
+		go scanOutput(reader, LineStdout, nil, newPhraseMatcher(Config{Phrase: "phrase"}, startTime), done, cancel, nil)
 
 		select {
 		case <-done:
@@ -187,3 +193,732 @@ func TestScanOutput(t *testing.T) {
 		}
 	})
 }
+
+func TestCommandString(t *testing.T) {
+	tests := []struct {
+		command  []string
+		expected string
+	}{
+		{[]string{"echo", "hello"}, "echo hello"},
+		{[]string{"echo", "hello world"}, "echo 'hello world'"},
+		{[]string{"echo", "it's"}, `echo 'it'\''s'`},
+		{[]string{"echo", ""}, "echo ''"},
+	}
+
+	for _, test := range tests {
+		result := Config{Command: test.command}.CommandString()
+		if result != test.expected {
+			t.Errorf("CommandString(%q) = %s, expected %s", test.command, result, test.expected)
+		}
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		result := Run(Config{Command: []string{"true"}}, 0)
+
+		if result.Status != StatusSuccess {
+			t.Errorf("Expected success, got %v", result.Status)
+		}
+		if result.ExitCode != 0 {
+			t.Errorf("Expected exit code 0, got %d", result.ExitCode)
+		}
+		if result.Reason() != "" {
+			t.Errorf("Expected no reason for success, got %q", result.Reason())
+		}
+	})
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		result := Run(Config{Command: []string{"sh", "-c", "exit 3"}}, 0)
+
+		if result.Found {
+			t.Error("Expected non-zero exit to count as a failed run")
+		}
+		if result.Status != StatusNonZeroExit {
+			t.Errorf("Expected non-zero exit status, got %v", result.Status)
+		}
+		if result.ExitCode != 3 {
+			t.Errorf("Expected exit code 3, got %d", result.ExitCode)
+		}
+		if result.Reason() != "exited with code 3" {
+			t.Errorf("Expected exit code reason, got %q", result.Reason())
+		}
+	})
+
+	t.Run("ignore failure", func(t *testing.T) {
+		result := Run(Config{Command: []string{"sh", "-c", "exit 3"}, IgnoreFailure: true}, 0)
+
+		if !result.Found {
+			t.Error("Expected non-zero exit to be counted with IgnoreFailure")
+		}
+		if result.Status != StatusNonZeroExit || result.ExitCode != 3 {
+			t.Errorf("Expected exit code to still be recorded, got %v (%d)", result.Status, result.ExitCode)
+		}
+	})
+
+	t.Run("terminated by signal", func(t *testing.T) {
+		result := Run(Config{Command: []string{"sh", "-c", "kill -TERM $$"}}, 0)
+
+		if result.Status != StatusNonZeroExit {
+			t.Errorf("Expected non-zero exit status, got %v", result.Status)
+		}
+		if result.Signal != "terminated" {
+			t.Errorf("Expected terminated signal, got %q", result.Signal)
+		}
+	})
+
+	t.Run("start failure", func(t *testing.T) {
+		result := Run(Config{Command: []string{"nonexistent-command-12345"}}, 0)
+
+		if result.Status != StatusStartFailure {
+			t.Errorf("Expected start failure, got %v", result.Status)
+		}
+		if result.Err == nil {
+			t.Error("Expected start error to be recorded")
+		}
+		if !strings.Contains(result.Reason(), "failed to start") {
+			t.Errorf("Expected start failure reason, got %q", result.Reason())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		result := Run(Config{Command: []string{"sleep", "1"}, Timeout: 100 * time.Millisecond}, 0)
+
+		if result.Status != StatusTimeout {
+			t.Errorf("Expected timeout, got %v", result.Status)
+		}
+	})
+
+	t.Run("phrase not found before exit", func(t *testing.T) {
+		result := Run(Config{Command: []string{"sh", "-c", "echo other; exit 2"}, Phrase: "ready"}, 0)
+
+		if result.Status != StatusPhraseNotFound {
+			t.Errorf("Expected phrase not found, got %v", result.Status)
+		}
+		if result.ExitCode != 2 {
+			t.Errorf("Expected exit code 2, got %d", result.ExitCode)
+		}
+		if !strings.Contains(result.Reason(), "before exit") {
+			t.Errorf("Expected exit to be mentioned in reason, got %q", result.Reason())
+		}
+	})
+}
+
+func TestStream(t *testing.T) {
+	t.Run("completion mode", func(t *testing.T) {
+		lines := make(chan Line, 10)
+		result, err := StreamContext(context.Background(), Config{Command: []string{"sh", "-c", "echo out; echo err >&2"}}, 0, lines)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if result.Status != StatusSuccess {
+			t.Errorf("Expected success, got %v", result.Status)
+		}
+
+		var stdout, stderr []string
+		for line := range lines {
+			switch line.Kind {
+			case LineStdout:
+				stdout = append(stdout, line.Text)
+			case LineStderr:
+				stderr = append(stderr, line.Text)
+			}
+		}
+		if len(stdout) != 1 || stdout[0] != "out" {
+			t.Errorf("Expected stdout line, got %q", stdout)
+		}
+		if len(stderr) != 1 || stderr[0] != "err" {
+			t.Errorf("Expected stderr line, got %q", stderr)
+		}
+	})
+
+	t.Run("phrase mode", func(t *testing.T) {
+		lines := make(chan Line, 10)
+		result, err := StreamContext(context.Background(), Config{Command: []string{"sh", "-c", "echo starting; echo ready; sleep 5"}, Phrase: "ready", Timeout: 5 * time.Second}, 0, lines)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if result.Status != StatusSuccess {
+			t.Errorf("Expected success, got %v", result.Status)
+		}
+
+		var texts []string
+		matched := false
+		for line := range lines {
+			texts = append(texts, line.Text)
+			if line.Kind == LineMatch {
+				matched = true
+			}
+		}
+		if !matched {
+			t.Errorf("Expected match marker, got %q", texts)
+		}
+		if texts[0] != "starting" {
+			t.Errorf("Expected output before match, got %q", texts)
+		}
+	})
+}
+
+func TestRunContext(t *testing.T) {
+	t.Run("start failure", func(t *testing.T) {
+		result, err := RunContext(context.Background(), Config{Command: []string{"nonexistent-command-12345"}}, 0)
+		if err == nil {
+			t.Error("Expected an error for a command that cannot start")
+		}
+		if result.Status != StatusStartFailure {
+			t.Errorf("Expected start failure, got %v", result.Status)
+		}
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := RunContext(ctx, Config{Command: []string{"true"}}, 0)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if result.Status != StatusCancelled || result.Found {
+			t.Errorf("Expected cancelled result, got %+v", result)
+		}
+	})
+
+	for _, phrase := range []string{"", "never printed"} {
+		t.Run(fmt.Sprintf("cancelled while running with phrase %q", phrase), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			result, err := RunContext(ctx, Config{Command: []string{"sleep", "5"}, Phrase: phrase}, 0)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+			}
+			if result.Status != StatusCancelled {
+				t.Errorf("Expected cancelled status, got %v", result.Status)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the command to be killed promptly, took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestMatchPhrase(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		line     string
+		matched  bool
+		text     string
+		captures map[string]string
+	}{
+		{"plain", Config{Phrase: "ready"}, "server ready", true, "ready", nil},
+		{"plain case sensitive", Config{Phrase: "ready"}, "server READY", false, "", nil},
+		{"plain ignoring case", Config{Phrase: "ready", IgnoreCase: true}, "server READY", true, "ready", nil},
+		{"regex", Config{PhraseRegex: regexp.MustCompile(`port \d+`)}, "Listening on port 8080", true, "port 8080", nil},
+		{"regex anchored", Config{PhraseRegex: regexp.MustCompile(`^port \d+`)}, "Listening on port 8080", false, "", nil},
+		{"regex captures", Config{PhraseRegex: regexp.MustCompile(`on (?P<host>\S+):(?P<port>\d+)`)}, "Listening on localhost:3000", true, "on localhost:3000",
+			map[string]string{"host": "localhost", "port": "3000"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, matched := test.config.matchPhrase(test.line)
+			if matched != test.matched {
+				t.Fatalf("Expected matched %v, got %v", test.matched, matched)
+			}
+			if match.text != test.text {
+				t.Errorf("Expected text %q, got %q", test.text, match.text)
+			}
+			if len(match.captures) != len(test.captures) {
+				t.Errorf("Expected captures %v, got %v", test.captures, match.captures)
+			}
+			for name, value := range test.captures {
+				if match.captures[name] != value {
+					t.Errorf("Expected capture %s=%q, got %q", name, value, match.captures[name])
+				}
+			}
+		})
+	}
+}
+
+func TestPhraseRegexResult(t *testing.T) {
+	config := Config{
+		Command:     []string{"sh", "-c", "echo starting; echo 'Listening on port 4321'; sleep 5"},
+		PhraseRegex: regexp.MustCompile(`port (?P<port>\d+)`),
+		Timeout:     5 * time.Second,
+	}
+	lines := make(chan Line, 10)
+	result, err := StreamContext(context.Background(), config, 0, lines)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !result.Found || result.Match != "port 4321" || result.Captures["port"] != "4321" {
+		t.Errorf("Expected match with port capture, got %+v", result)
+	}
+
+	var marker string
+	for line := range lines {
+		if line.Kind == LineMatch {
+			marker = line.Text
+		}
+	}
+	if marker != `Match found! "port 4321" (port=4321) on stdout` {
+		t.Errorf("Unexpected match marker %q", marker)
+	}
+}
+
+func TestPhraseStream(t *testing.T) {
+	tests := []struct {
+		stream   PhraseStream
+		expected LineKind
+		minimum  time.Duration
+	}{
+		{PhraseStreamBoth, LineStderr, 0},
+		{PhraseStreamStdout, LineStdout, 40 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stream.String(), func(t *testing.T) {
+			config := Config{
+				Command:      []string{"sh", "-c", "echo 'debug: waiting for ready' >&2; sleep 0.05; echo ready; sleep 5"},
+				Phrase:       "ready",
+				PhraseStream: tt.stream,
+				Timeout:      5 * time.Second,
+			}
+			result, err := RunContext(context.Background(), config, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !result.Found || result.MatchStream != tt.expected {
+				t.Errorf("Expected a match on %s, got %+v", tt.expected, result)
+			}
+			if result.Duration < tt.minimum {
+				t.Errorf("Expected the match after %v, got %v", tt.minimum, result.Duration)
+			}
+		})
+	}
+
+	for _, value := range []string{"stdout", "stderr", "both"} {
+		stream, err := ParsePhraseStream(value)
+		if err != nil || stream.String() != value {
+			t.Errorf("ParsePhraseStream(%q) = %v, %v", value, stream, err)
+		}
+	}
+	if _, err := ParsePhraseStream("stdin"); err == nil {
+		t.Error("Expected an error for stdin")
+	}
+}
+
+func TestStartPhrase(t *testing.T) {
+	config := Config{
+		Command:     []string{"sh", "-c", "echo migration finished last time; sleep 0.1; echo migration started; sleep 0.1; echo migration finished; sleep 5"},
+		StartPhrase: "migration started",
+		Phrase:      "migration finished",
+		Timeout:     5 * time.Second,
+	}
+	lines := make(chan Line, 10)
+	result, err := StreamContext(context.Background(), config, 50*time.Millisecond, lines)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Found {
+		t.Fatalf("Expected the interval to be timed, got %+v", result)
+	}
+	// The interval is timed between the phrases, without subtracting the
+	// shell overhead.
+	if result.Duration < 90*time.Millisecond || result.Duration > 190*time.Millisecond {
+		t.Errorf("Expected about 100ms between the phrases, got %v", result.Duration)
+	}
+
+	var markers []string
+	for line := range lines {
+		if line.Kind == LineMatch {
+			markers = append(markers, line.Text)
+		}
+	}
+	if len(markers) != 2 || markers[0] != `Start phrase found! "migration started" on stdout` {
+		t.Errorf("Expected a start and an end marker, got %q", markers)
+	}
+
+	config.Command = []string{"sh", "-c", "echo migration finished"}
+	if result := Run(config, 0); result.Found || result.Status != StatusPhraseNotFound {
+		t.Errorf("Expected the end phrase not to count before the start phrase, got %+v", result)
+	}
+	if config.PhraseString() != `"migration started" → "migration finished"` {
+		t.Errorf("Unexpected phrase description %q", config.PhraseString())
+	}
+}
+
+func TestCheckpointsInOneLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		phrases    []string
+		ignoreCase bool
+		reached    int
+		minSplit   time.Duration
+	}{
+		{"duplicate phrases", "echo ready; sleep 0.1; echo ready; sleep 5", []string{"ready", "ready"}, false, 2, 90 * time.Millisecond},
+		{"repeated on one line", "echo ready ready", []string{"ready", "ready"}, false, 2, 0},
+		{"in order on one line", "echo compiled and linked", []string{"compiled", "linked"}, false, 2, 0},
+		{"out of order on one line", "echo linked and compiled; sleep 0.05", []string{"compiled", "linked"}, false, 1, 0},
+		// The output goes idle in the middle of these lines, so the first
+		// piece is matched before the rest of the line arrives.
+		{"line in pieces", "printf ready; sleep 0.3; echo ' now'; sleep 0.05", []string{"ready", "ready"}, false, 1, 0},
+		{"repeated across pieces", "printf ready; sleep 0.3; echo ' ready'", []string{"ready", "ready"}, false, 2, 250 * time.Millisecond},
+		{"pieces ignoring case", "printf 'READY ȺȺȺȺȺȺ'; sleep 0.3; echo ' ready'", []string{"ready", "ready"}, true, 2, 250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Command: []string{"sh", "-c", tt.command}, IgnoreCase: tt.ignoreCase, Timeout: 5 * time.Second}
+			for i, phrase := range tt.phrases {
+				config.Checkpoints = append(config.Checkpoints, Checkpoint{Name: string(rune('a' + i)), Phrase: phrase})
+			}
+
+			result, _ := RunContext(context.Background(), config, 0)
+			if len(result.Checkpoints) != tt.reached {
+				t.Fatalf("Expected %d checkpoints to be reached, got %+v", tt.reached, result.Checkpoints)
+			}
+			if result.Found != (tt.reached == len(tt.phrases)) {
+				t.Errorf("Unexpected result %+v", result)
+			}
+			if tt.reached == 2 {
+				if split := result.Checkpoints[1].Elapsed - result.Checkpoints[0].Elapsed; split < tt.minSplit {
+					t.Errorf("Expected a split of at least %v, got %v", tt.minSplit, split)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpoints(t *testing.T) {
+	config := Config{
+		Command: []string{"sh", "-c", "echo ready too early >&2; sleep 0.05; echo config loaded; sleep 0.05; echo db connected >&2; sleep 0.05; echo server ready; sleep 5"},
+		Checkpoints: []Checkpoint{
+			{Name: "config", Phrase: "config loaded"},
+			{Name: "db", Phrase: "db connected"},
+			{Name: "ready", Phrase: "ready"},
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	lines := make(chan Line, 20)
+	result, err := StreamContext(context.Background(), config, 0, lines)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Found {
+		t.Fatalf("Expected every checkpoint to be reached, got %+v", result)
+	}
+	if len(result.Checkpoints) != 3 {
+		t.Fatalf("Expected 3 checkpoints, got %+v", result.Checkpoints)
+	}
+	for i, name := range []string{"config", "db", "ready"} {
+		if result.Checkpoints[i].Name != name {
+			t.Errorf("Expected checkpoint %d to be %s, got %s", i, name, result.Checkpoints[i].Name)
+		}
+	}
+	if result.Checkpoints[0].Elapsed < 40*time.Millisecond {
+		t.Errorf("Expected the early 'ready' line not to count, got %+v", result.Checkpoints)
+	}
+	if result.Duration != result.Checkpoints[2].Elapsed {
+		t.Errorf("Expected the run to end at the last checkpoint, got %v and %v", result.Duration, result.Checkpoints[2].Elapsed)
+	}
+
+	var markers []string
+	for line := range lines {
+		if line.Kind == LineMatch {
+			markers = append(markers, line.Text)
+		}
+	}
+	if len(markers) != 3 || markers[2] != "Checkpoint reached: ready (3/3) on stdout" {
+		t.Errorf("Expected a marker for every checkpoint, got %q", markers)
+	}
+}
+
+func TestCheckpointStatistics(t *testing.T) {
+	config := Config{Checkpoints: []Checkpoint{{Name: "a", Phrase: "a"}, {Name: "b", Phrase: "b"}}}
+	results := []Result{
+		{Found: true, Checkpoints: []CheckpointTime{{"a", 100 * time.Millisecond}, {"b", 300 * time.Millisecond}}},
+		{Found: true, Checkpoints: []CheckpointTime{{"a", 200 * time.Millisecond}, {"b", 300 * time.Millisecond}}},
+		{Found: false, Checkpoints: []CheckpointTime{{"a", time.Second}}},
+	}
+
+	summaries := CheckpointStatistics(results, config)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summaries, got %+v", summaries)
+	}
+	if summaries[0].Time.Mean != 150*time.Millisecond {
+		t.Errorf("Expected mean time to a of 150ms, got %v", summaries[0].Time.Mean)
+	}
+	if summaries[1].Split.Mean != 150*time.Millisecond || summaries[1].Split.Min != 100*time.Millisecond {
+		t.Errorf("Expected splits of 100ms and 200ms for b, got %+v", summaries[1].Split)
+	}
+}
+
+func TestFirstOutput(t *testing.T) {
+	config := Config{
+		Command: []string{"sh", "-c", "sleep 0.05; echo starting; sleep 0.1; echo warning >&2"},
+		Timeout: 5 * time.Second,
+	}
+	result, err := RunContext(context.Background(), config, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stdout, ok := result.FirstOutput[LineStdout]
+	if !ok || stdout < 40*time.Millisecond {
+		t.Errorf("Expected the first stdout after 50ms, got %v", result.FirstOutput)
+	}
+	stderr, ok := result.FirstOutput[LineStderr]
+	if !ok || stderr < stdout+80*time.Millisecond {
+		t.Errorf("Expected the first stderr 100ms after stdout, got %v", result.FirstOutput)
+	}
+
+	config.Command = []string{"sh", "-c", "sleep 0.05; echo ready"}
+	result, err = RunContext(context.Background(), config, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := result.FirstOutput[LineStderr]; ok {
+		t.Errorf("Expected no first stderr for a command that wrote none, got %v", result.FirstOutput)
+	}
+
+	config.Output = OutputNull
+	result, err = RunContext(context.Background(), config, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.FirstOutput != nil {
+		t.Errorf("Expected no first output when the output is discarded, got %v", result.FirstOutput)
+	}
+
+	results := []Result{
+		{Found: true, FirstOutput: map[LineKind]time.Duration{LineStdout: 100 * time.Millisecond}},
+		{Found: true, FirstOutput: map[LineKind]time.Duration{LineStdout: 300 * time.Millisecond}},
+		{Found: false, FirstOutput: map[LineKind]time.Duration{LineStdout: time.Second}},
+	}
+	first, ok := FirstOutputStatistics(results, LineStdout)
+	if !ok || first.Mean != 200*time.Millisecond {
+		t.Errorf("Expected a mean of 200ms over the counted runs, got %+v", first)
+	}
+	if _, ok := FirstOutputStatistics(results, LineStderr); ok {
+		t.Error("Expected no statistics for a stream without output")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"completion", Config{Command: []string{"sh", "-c", "echo building; sleep 0.1; echo linking; sleep 5"}}},
+		{"phrase", Config{Command: []string{"sh", "-c", "echo building; sleep 0.1; echo linking; sleep 5"}, Phrase: "done"}},
+		{"null output", Config{Command: []string{"sh", "-c", "echo building; sleep 0.1; echo linking; sleep 5"}, Output: OutputNull}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.IdleTimeout = 200 * time.Millisecond
+			config.Timeout = 5 * time.Second
+
+			lines := make(chan Line, 10)
+			start := time.Now()
+			result, err := StreamContext(context.Background(), config, 0, lines)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the run to stop when it stalled, took %v", elapsed)
+			}
+			if result.Found || result.Status != StatusStalled {
+				t.Fatalf("Expected the run to stall, got %+v", result)
+			}
+			if result.StalledAt < 90*time.Millisecond || result.StalledAt > time.Second {
+				t.Errorf("Expected the stall after the last output at 100ms, got %v", result.StalledAt)
+			}
+			if !strings.HasPrefix(result.Reason(), "stalled: no output after ") {
+				t.Errorf("Unexpected reason %q", result.Reason())
+			}
+
+			var marker string
+			for line := range lines {
+				if line.Kind == LineFailure {
+					marker = line.Text
+				}
+			}
+			if !strings.HasPrefix(marker, "Stalled! No output for 200ms after ") {
+				t.Errorf("Unexpected stall marker %q", marker)
+			}
+		})
+	}
+
+	config := Config{
+		Command:     []string{"sh", "-c", "for i in 1 2 3 4 5; do echo $i; sleep 0.05; done"},
+		IdleTimeout: 200 * time.Millisecond,
+		Timeout:     5 * time.Second,
+	}
+	if result := Run(config, 0); result.Status != StatusSuccess {
+		t.Errorf("Expected a command that keeps writing not to stall, got %+v", result)
+	}
+}
+
+func TestUntilQuiet(t *testing.T) {
+	config := Config{
+		Command:    []string{"sh", "-c", "sleep 0.05; echo loading; sleep 0.05; echo started >&2; sleep 5"},
+		UntilQuiet: 300 * time.Millisecond,
+		Timeout:    5 * time.Second,
+	}
+	lines := make(chan Line, 10)
+	start := time.Now()
+	result, err := StreamContext(context.Background(), config, 0, lines)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the run to end once the output went quiet, took %v", elapsed)
+	}
+	if !result.Found || result.Status != StatusSuccess {
+		t.Fatalf("Expected the run to succeed, got %+v", result)
+	}
+	if result.Duration < 90*time.Millisecond || result.Duration > 250*time.Millisecond {
+		t.Errorf("Expected the run timed to the last output at 100ms, not the end of the window, got %v", result.Duration)
+	}
+
+	var marker string
+	for line := range lines {
+		if line.Kind == LineMatch {
+			marker = line.Text
+		}
+	}
+	if !strings.HasPrefix(marker, "Quiet! No output for 300ms after ") {
+		t.Errorf("Unexpected quiet marker %q", marker)
+	}
+
+	// A command that exits is timed to its last output, and only counts if
+	// it succeeded.
+	config.Command = []string{"sh", "-c", "echo loading; sleep 0.05; echo started; sleep 0.1; exit 3"}
+	result = Run(config, 0)
+	if result.Found || result.Status != StatusNonZeroExit {
+		t.Errorf("Expected the failed exit to count against the run, got %+v", result)
+	}
+	if result.Duration < 40*time.Millisecond || result.Duration > 140*time.Millisecond {
+		t.Errorf("Expected the run timed to the last output at 50ms, got %v", result.Duration)
+	}
+
+	// Silence before the first output does not count.
+	config.Command = []string{"sh", "-c", "sleep 0.4; echo late; sleep 5"}
+	result = Run(config, 0)
+	if !result.Found || result.Duration < 350*time.Millisecond {
+		t.Errorf("Expected the run timed to the late output, got %+v", result)
+	}
+}
+
+func TestStdin(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(inputFile, []byte("from file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"null", Config{}, "<eof>"},
+		{"file", Config{Stdin: StdinFile, Input: inputFile}, "from file"},
+		{"string", Config{Stdin: StdinString, Input: "from string\n"}, "from string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Command = []string{"sh", "-c", "read line && echo \"$line\" || echo '<eof>'"}
+
+			// Run twice to check that the input is provided afresh every run.
+			for range 2 {
+				lines := make(chan Line, 10)
+				result, err := StreamContext(context.Background(), config, 0, lines)
+				if err != nil || !result.Found {
+					t.Fatalf("Expected success, got %+v (%v)", result, err)
+				}
+				var output []string
+				for line := range lines {
+					output = append(output, line.Text)
+				}
+				if len(output) != 1 || output[0] != test.expected {
+					t.Errorf("Expected %q on stdout, got %q", test.expected, output)
+				}
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		result, err := RunContext(context.Background(), Config{Command: []string{"cat"}, Stdin: StdinFile, Input: filepath.Join(t.TempDir(), "missing")}, 0)
+		if err == nil || result.Status != StatusStartFailure {
+			t.Errorf("Expected a start failure, got %+v (%v)", result, err)
+		}
+	})
+}
+
+func TestOutput(t *testing.T) {
+	t.Run("null", func(t *testing.T) {
+		lines := make(chan Line, 10)
+		config := Config{Command: []string{"echo", "discarded"}, Output: OutputNull}
+		if result, err := StreamContext(context.Background(), config, 0, lines); err != nil || !result.Found {
+			t.Fatalf("Expected success, got %+v (%v)", result, err)
+		}
+		for line := range lines {
+			t.Errorf("Expected no output to be read, got %q", line.Text)
+		}
+	})
+
+	t.Run("null read for another option", func(t *testing.T) {
+		config := Config{Output: OutputNull}
+		if description := config.OutputString(); description != "discarded (null), the cheapest option" {
+			t.Errorf("Unexpected description %q", description)
+		}
+		config.FailPhrases = []string{"panic:"}
+		config.IdleTimeout = time.Second
+		if description := config.OutputString(); !strings.HasPrefix(description, "piped for --fail-phrase, --idle-timeout and then discarded") {
+			t.Errorf("Expected the description to say the output is piped, got %q", description)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		config := Config{Command: []string{"echo", "written"}, Output: OutputFile, OutputFile: filepath.Join(dir, "out-{run}.log"), RunLabel: "3"}
+		if result, err := RunContext(context.Background(), config, 0); err != nil || !result.Found {
+			t.Fatalf("Expected success, got %+v (%v)", result, err)
+		}
+		contents, err := os.ReadFile(filepath.Join(dir, "out-3.log"))
+		if err != nil || string(contents) != "written\n" {
+			t.Errorf("Expected the output in out-3.log, got %q (%v)", contents, err)
+		}
+	})
+
+	t.Run("file with phrase", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.log")
+		config := Config{Command: []string{"sh", "-c", "echo before; echo ready"}, Phrase: "ready", Output: OutputFile, OutputFile: path}
+		if result, err := RunContext(context.Background(), config, 0); err != nil || !result.Found {
+			t.Fatalf("Expected success, got %+v (%v)", result, err)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil || !strings.HasPrefix(string(contents), "before\n") {
+			t.Errorf("Expected scanned lines to be copied to the file, got %q (%v)", contents, err)
+		}
+	})
+
+	t.Run("unwritable file", func(t *testing.T) {
+		config := Config{Command: []string{"true"}, Output: OutputFile, OutputFile: filepath.Join(t.TempDir(), "missing", "out.log")}
+		if result, err := RunContext(context.Background(), config, 0); err == nil || result.Status != StatusStartFailure {
+			t.Errorf("Expected a start failure, got %+v (%v)", result, err)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package benchmark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// readBufferSize is how much output is read at a time.
+	readBufferSize = 32 * 1024
+	// maxLineLength bounds how much of a line is held before it is shown
+	// in pieces. Phrases are still found across the pieces.
+	maxLineLength = 1024 * 1024
+	// matchOverlap is how much of the part of a line that has already been
+	// shown is kept to match phrases that continue into the rest of it.
+	matchOverlap = 64 * 1024
+	// partialLineIdle is how long the output has to stop in the middle of a
+	// line before that line is matched without its line break, as prompts
+	// are.
+	partialLineIdle = 100 * time.Millisecond
+)
+
+func (k LineKind) String() string {
+	switch k {
+	case LineStdout:
+		return "stdout"
+	case LineStderr:
+		return "stderr"
+	case LineFailure:
+		return "failure"
+	default:
+		return "match"
+	}
+}
+
+// outputScanner splits output into lines as it arrives, treating "\n", "\r\n"
+// and a lone "\r" as line breaks, and looks for the phrase in every complete
+// line. A line being written is only matched once the output goes idle in the
+// middle of it, so a phrase is found in a prompt that has no line break, or
+// once it is too long to hold. Matches are timed from when the line was read.
+type outputScanner struct {
+	kind    LineKind
+	matcher *phraseMatcher
+	found   chan phraseMatch
+	cancel  chan struct{}
+	lines   chan<- Line
+
+	// line is the part of the current line that has not been shown yet, and
+	// shown the end of the part that has, which still counts when matching.
+	line  []byte
+	shown []byte
+	// lineAt is when the end of the current line was read.
+	lineAt time.Time
+	// carriageReturn records that the last chunk ended with "\r", so that a
+	// "\n" at the start of the next one belongs to the same line break.
+	carriageReturn bool
+}
+
+// chunk is the result of one read of the output.
+type chunk struct {
+	data []byte
+	err  error
+	at   time.Time
+}
+
+// scanOutput reads output until EOF, the phrase is found or cancel is closed,
+// copying it unchanged to tee. It returns any error reading the output, other
+// than the reader being closed once the run is over.
+func scanOutput(reader io.Reader, kind LineKind, tee io.Writer, matcher *phraseMatcher, found chan phraseMatch, cancel chan struct{}, lines chan<- Line) error {
+	s := &outputScanner{kind: kind, matcher: matcher, found: found, cancel: cancel, lines: lines}
+
+	chunks := make(chan chunk)
+	next := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go readChunks(reader, chunks, next, done)
+
+	idle := time.NewTimer(partialLineIdle)
+	idle.Stop()
+	defer idle.Stop()
+
+	for {
+		var idleC <-chan time.Time
+		if s.matcher != nil && len(s.line) > 0 {
+			idleC = idle.C
+		}
+
+		select {
+		case c := <-chunks:
+			if s.cancelled() {
+				return nil
+			}
+			if len(c.data) > 0 {
+				if tee != nil {
+					tee.Write(c.data)
+				}
+				if s.write(c.data, c.at) {
+					return nil
+				}
+			}
+
+			switch {
+			case c.err == nil:
+			case c.err == io.EOF || errors.Is(c.err, syscall.EIO):
+				// The master of a pseudo-terminal fails with EIO rather
+				// than reporting EOF once the command has closed the other
+				// end.
+				s.flush()
+				return nil
+			case errors.Is(c.err, os.ErrClosed) || s.cancelled():
+				return nil
+			default:
+				s.flush()
+				return fmt.Errorf("reading %s: %w", kind, c.err)
+			}
+
+			idle.Reset(partialLineIdle)
+			next <- struct{}{}
+		case <-idleC:
+			if s.check(false) {
+				return nil
+			}
+		case <-cancel:
+			return nil
+		}
+	}
+}
+
+// readChunks reads the output in the background, so that scanOutput notices
+// it going idle in the middle of a line. The buffer is only reused once the
+// last chunk has been processed and next is signalled.
+func readChunks(reader io.Reader, chunks chan<- chunk, next, done <-chan struct{}) {
+	buffer := make([]byte, readBufferSize)
+	for {
+		n, err := reader.Read(buffer)
+		select {
+		case chunks <- chunk{data: buffer[:n], err: err, at: time.Now()}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case <-next:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *outputScanner) cancelled() bool {
+	select {
+	case <-s.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// write processes a chunk of output read at at, and reports whether scanning
+// is over because the phrase was found or the scan was cancelled.
+func (s *outputScanner) write(data []byte, at time.Time) bool {
+	s.lineAt = at
+	if s.carriageReturn && len(data) > 0 && data[0] == '\n' {
+		data = data[1:]
+	}
+	s.carriageReturn = false
+
+	for len(data) > 0 {
+		end := bytes.IndexAny(data, "\r\n")
+		if end < 0 {
+			s.line = append(s.line, data...)
+			if len(s.line) >= maxLineLength {
+				return s.check(false)
+			}
+			return false
+		}
+
+		s.line = append(s.line, data[:end]...)
+		if data[end] == '\r' {
+			if end+1 == len(data) {
+				s.carriageReturn = true
+			} else if data[end+1] == '\n' {
+				end++
+			}
+		}
+		data = data[end+1:]
+
+		if s.check(true) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush shows the rest of the output once no more will arrive.
+func (s *outputScanner) flush() {
+	if len(s.line) > 0 {
+		s.check(true)
+	}
+}
+
+// check matches the current line, which is complete or, when the output went
+// idle or the line is too long to hold, not. The line is shown when it is
+// complete, too long to hold or reaches a checkpoint, and reports whether
+// scanning is over.
+func (s *outputScanner) check(complete bool) bool {
+	if complete && len(s.line) == 0 && len(s.shown) > 0 {
+		// The line was already shown up to its line break.
+		s.shown = s.shown[:0]
+		s.endLine()
+		return false
+	}
+
+	var markers []string
+	var match phraseMatch
+	found := false
+	if s.matcher != nil && (complete || len(s.line) > 0) {
+		text := string(s.shown) + string(s.line)
+		markers, match, found = s.matcher.match(text, s.kind, s.lineAt)
+	}
+
+	if !complete && !found && len(markers) == 0 && len(s.line) < maxLineLength {
+		return false
+	}
+
+	if !s.send(Line{Kind: s.kind, Text: string(s.line)}) {
+		return true
+	}
+	if complete {
+		s.line, s.shown = s.line[:0], s.shown[:0]
+		s.endLine()
+	} else {
+		s.shown = append(s.shown, s.line...)
+		if dropped := len(s.shown) - matchOverlap; dropped > 0 {
+			s.shown = append(s.shown[:0], s.shown[dropped:]...)
+			if s.matcher != nil {
+				s.matcher.dropLine(s.kind, dropped)
+			}
+		}
+		s.line = s.line[:0]
+	}
+
+	if !found {
+		for _, marker := range markers {
+			s.send(Line{Kind: LineMatch, Text: marker})
+		}
+		return false
+	}
+
+	kind := LineMatch
+	if match.failure != "" {
+		kind = LineFailure
+	}
+	select {
+	case s.found <- match:
+		for _, marker := range markers {
+			s.send(Line{Kind: kind, Text: marker})
+		}
+	default:
+	}
+	return true
+}
+
+// endLine tells the matcher that the next line starts afresh.
+func (s *outputScanner) endLine() {
+	if s.matcher != nil {
+		s.matcher.endLine(s.kind)
+	}
+}
+
+func (s *outputScanner) send(line Line) bool {
+	return sendLine(s.lines, line, s.cancel)
+}
+
+func sendLine(lines chan<- Line, line Line, cancel <-chan struct{}) bool {
+	if lines == nil {
+		return true
+	}
+	select {
+	case lines <- line:
+		return true
+	case <-cancel:
+		return false
+	}
+}
@@ -0,0 +1,207 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chunkedReader returns its chunks one Read at a time, then err.
+type chunkedReader struct {
+	chunks []string
+	err    error
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.chunks[0])
+	if n < len(r.chunks[0]) {
+		r.chunks[0] = r.chunks[0][n:]
+	} else {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+func scanLines(t *testing.T, reader io.Reader, matcher *phraseMatcher) ([]string, bool, error) {
+	t.Helper()
+	lines := make(chan Line, 100)
+	found := make(chan phraseMatch, 1)
+	err := scanOutput(reader, LineStdout, nil, matcher, found, make(chan struct{}), lines)
+	close(lines)
+
+	var texts []string
+	for line := range lines {
+		if line.Kind != LineMatch {
+			texts = append(texts, line.Text)
+		}
+	}
+	select {
+	case <-found:
+		return texts, true, err
+	default:
+		return texts, false, err
+	}
+}
+
+func TestScanOutputLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []string
+		expected []string
+	}{
+		{"newlines", []string{"one\ntwo\n"}, []string{"one", "two"}},
+		{"no trailing newline", []string{"one\ntwo"}, []string{"one", "two"}},
+		{"carriage returns", []string{"10%\r50%\r100%\n"}, []string{"10%", "50%", "100%"}},
+		{"crlf", []string{"one\r\ntwo\r\n"}, []string{"one", "two"}},
+		{"crlf split across reads", []string{"one\r", "\ntwo\n"}, []string{"one", "two"}},
+		{"line split across reads", []string{"on", "e\ntw", "o\n"}, []string{"one", "two"}},
+		{"empty lines", []string{"one\n\ntwo\n"}, []string{"one", "", "two"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, _, err := scanLines(t, &chunkedReader{chunks: test.chunks, err: io.EOF}, nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Equal(lines, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, lines)
+			}
+		})
+	}
+}
+
+func TestScanOutputLongLines(t *testing.T) {
+	t.Run("phrase at the end of a long line", func(t *testing.T) {
+		line := strings.Repeat("x", 3*maxLineLength) + " ready"
+		_, found, err := scanLines(t, strings.NewReader(line+"\n"), newPhraseMatcher(Config{Phrase: "ready"}, time.Now()))
+		if err != nil || !found {
+			t.Errorf("Expected the phrase to be found, got found=%v err=%v", found, err)
+		}
+	})
+
+	t.Run("phrase across the pieces of a long line", func(t *testing.T) {
+		line := strings.Repeat("x", maxLineLength-3) + "server ready" + strings.Repeat("y", 10)
+		_, found, err := scanLines(t, strings.NewReader(line), newPhraseMatcher(Config{Phrase: "server ready"}, time.Now()))
+		if err != nil || !found {
+			t.Errorf("Expected the phrase to be found, got found=%v err=%v", found, err)
+		}
+	})
+
+	t.Run("long lines are shown in pieces", func(t *testing.T) {
+		lines, _, _ := scanLines(t, strings.NewReader(strings.Repeat("x", 2*maxLineLength+10)+"\n"), nil)
+		if len(lines) != 3 || strings.Join(lines, "") != strings.Repeat("x", 2*maxLineLength+10) {
+			t.Errorf("Expected the line in 3 pieces, got %d", len(lines))
+		}
+	})
+}
+
+func TestScanOutputReadError(t *testing.T) {
+	readErr := errors.New("device unplugged")
+	lines, _, err := scanLines(t, &chunkedReader{chunks: []string{"partial"}, err: readErr}, nil)
+	if !errors.Is(err, readErr) || !strings.Contains(err.Error(), "reading stdout") {
+		t.Errorf("Expected the read error to be reported, got %v", err)
+	}
+	if !slices.Equal(lines, []string{"partial"}) {
+		t.Errorf("Expected the output read before the error, got %q", lines)
+	}
+}
+
+func TestPhraseInPartialLine(t *testing.T) {
+	config := Config{
+		Command: []string{"sh", "-c", "printf 'Password: '; sleep 5"},
+		Phrase:  "Password:",
+		Timeout: 3 * time.Second,
+	}
+	lines := make(chan Line, 10)
+	result, err := StreamContext(context.Background(), config, 0, lines)
+	if err != nil || !result.Found {
+		t.Fatalf("Expected the phrase to be found without a line break, got %+v (%v)", result, err)
+	}
+	if result.Duration > time.Second {
+		t.Errorf("Expected the phrase to be found as soon as it was written, took %v", result.Duration)
+	}
+	if line := <-lines; line.Text != "Password: " {
+		t.Errorf("Expected the partial line to be shown, got %q", line.Text)
+	}
+}
+
+func TestScanOutputMatchesCompleteLines(t *testing.T) {
+	t.Run("capture split across reads", func(t *testing.T) {
+		matcher := newPhraseMatcher(Config{PhraseRegex: regexp.MustCompile(`port (?P<port>\d+)`)}, time.Now())
+		found := make(chan phraseMatch, 1)
+		reader := &chunkedReader{chunks: []string{"listening on port 80", "80\n"}, err: io.EOF}
+		if err := scanOutput(reader, LineStdout, nil, matcher, found, make(chan struct{}), nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		select {
+		case match := <-found:
+			if match.captures["port"] != "8080" {
+				t.Errorf("Expected port=8080, got %v", match.captures)
+			}
+		default:
+			t.Error("Expected the phrase to be found")
+		}
+	})
+
+	t.Run("anchor at the end of a line", func(t *testing.T) {
+		config := Config{
+			Command:     []string{"sh", "-c", "printf 'not ready'; sleep 0.01; echo ' yet'; sleep 0.3; echo ready; sleep 5"},
+			PhraseRegex: regexp.MustCompile(`ready$`),
+			Timeout:     5 * time.Second,
+		}
+		result, err := RunContext(context.Background(), config, 0)
+		if err != nil || !result.Found {
+			t.Fatalf("Expected the phrase to be found, got %+v (%v)", result, err)
+		}
+		if result.Duration < 250*time.Millisecond {
+			t.Errorf("Expected the match on the later line, got %v", result.Duration)
+		}
+	})
+}
+
+func TestScanOutputIdleLineMatchedOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		rest    string
+		reached int
+	}{
+		{"phrase not repeated", " y\n", 1},
+		{"phrase repeated", " x\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Checkpoints: []Checkpoint{{Name: "a", Phrase: "x"}, {Name: "b", Phrase: "x"}}}
+			matcher := newPhraseMatcher(config, time.Now())
+			reader, writer := io.Pipe()
+			go func() {
+				writer.Write([]byte("x"))
+				// The output goes idle in the middle of the line, so what
+				// has arrived of it is matched before the rest.
+				time.Sleep(3 * partialLineIdle)
+				writer.Write([]byte(tt.rest))
+				writer.Close()
+			}()
+
+			_, found, err := scanLines(t, reader, matcher)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if reached := matcher.checkpoints(0); len(reached) != tt.reached {
+				t.Errorf("Expected %d checkpoints to be reached, got %+v", tt.reached, reached)
+			}
+			if found != (tt.reached == 2) {
+				t.Errorf("Unexpected found=%v", found)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package benchmark
+
+import (
+	"os"
+	"os/exec"
+)
+
+const fallbackShell = "/bin/sh"
+
+// ResolveShell turns the value of --shell into the shell that runs commands:
+// "default" is the user's $SHELL and "none" runs commands directly.
+func ResolveShell(name string) string {
+	switch name {
+	case "none", "":
+		return ""
+	case "default":
+		return defaultShell()
+	default:
+		return name
+	}
+}
+
+func defaultShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return fallbackShell
+}
+
+// isScript reports whether the command is a --command string for the shell to
+// parse, so that it can contain pipes, redirects and globs. Positional
+// arguments are quoted instead, and reach the command unchanged.
+func (c Config) isScript() bool {
+	return c.Script && c.Shell != "" && len(c.Command) == 1
+}
+
+func (c Config) command() *exec.Cmd {
+	var cmd *exec.Cmd
+	if c.Shell == "" {
+		cmd = exec.Command(c.Command[0], c.Command[1:]...)
+	} else {
+		cmd = exec.Command(c.Shell, "-c", c.CommandString())
+	}
+	c.applyEnvironment(cmd)
+	return cmd
+}
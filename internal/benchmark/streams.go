@@ -0,0 +1,267 @@
+package benchmark
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainTimeout bounds how long output is still read after the command has
+// exited, in case a background process inherited its stdout or stderr.
+const drainTimeout = 250 * time.Millisecond
+
+type outputStreams struct {
+	readers  []*os.File
+	writers  []*os.File
+	kinds    []LineKind
+	copies   []io.Writer
+	cancel   chan struct{}
+	scanners sync.WaitGroup
+	once     sync.Once
+
+	mu    sync.Mutex
+	err   error
+	first map[LineKind]time.Time
+	// lastOutput is when output was last read from any stream, in
+	// nanoseconds since the Unix epoch.
+	lastOutput atomic.Int64
+}
+
+// activityReader records when output was first read from reader, and every
+// read in the streams' lastOutput.
+type activityReader struct {
+	reader  io.Reader
+	streams *outputStreams
+	first   time.Time
+}
+
+func (r *activityReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		now := time.Now()
+		if r.first.IsZero() {
+			r.first = now
+		}
+		r.streams.lastOutput.Store(now.UnixNano())
+	}
+	return n, err
+}
+
+// attachOutputStreams connects the command's stdout and stderr to pipes that
+// are scanned by chrono. Lines are copied on to any writers the command's
+// output was already directed to.
+func attachOutputStreams(cmd *exec.Cmd) (*outputStreams, error) {
+	s := &outputStreams{cancel: make(chan struct{}), copies: []io.Writer{cmd.Stdout, cmd.Stderr}}
+
+	for _, kind := range []LineKind{LineStdout, LineStderr} {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			s.abort()
+			return nil, err
+		}
+		s.readers = append(s.readers, reader)
+		s.writers = append(s.writers, writer)
+		s.kinds = append(s.kinds, kind)
+	}
+
+	cmd.Stdout = s.writers[0]
+	cmd.Stderr = s.writers[1]
+	return s, nil
+}
+
+// attachPTY connects the command's stdout and stderr to a pseudo-terminal,
+// whose output is scanned as stdout.
+func attachPTY(cmd *exec.Cmd, config Config) (*outputStreams, error) {
+	master, slave, err := openPTY(config.PTYColumns, config.PTYRows)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &outputStreams{
+		cancel:  make(chan struct{}),
+		readers: []*os.File{master},
+		writers: []*os.File{slave},
+		kinds:   []LineKind{LineStdout},
+		copies:  []io.Writer{cmd.Stdout},
+	}
+	cmd.Stdout, cmd.Stderr = slave, slave
+	useControllingTerminal(cmd)
+	return s, nil
+}
+
+// closeChildEnds closes the parent's copies of the write ends once the child
+// has started, so that readers see EOF when the child exits.
+func (s *outputStreams) closeChildEnds() {
+	if s == nil {
+		return
+	}
+	for _, writer := range s.writers {
+		writer.Close()
+	}
+}
+
+func (s *outputStreams) scan(matcher *phraseMatcher, found chan phraseMatch, lines chan<- Line) {
+	if s == nil {
+		return
+	}
+	for i, reader := range s.readers {
+		s.scanners.Add(1)
+		go func() {
+			defer s.scanners.Done()
+			activity := &activityReader{reader: reader, streams: s}
+			err := scanOutput(activity, s.kinds[i], s.copies[i], matcher, found, s.cancel, lines)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.err = errors.Join(s.err, err)
+			if !activity.first.IsZero() {
+				if s.first == nil {
+					s.first = make(map[LineKind]time.Time)
+				}
+				s.first[s.kinds[i]] = activity.first
+			}
+		}()
+	}
+}
+
+// stalled returns a channel that receives the time output was last read, once
+// none has been read for timeout since then or since start. It never receives
+// without a timeout.
+func (s *outputStreams) stalled(start time.Time, timeout time.Duration) <-chan time.Time {
+	if s == nil || timeout <= 0 {
+		return nil
+	}
+	return s.silence(timeout, start)
+}
+
+// quiet returns a channel that receives the time output was last read, once
+// the command has written something and then nothing for window. It never
+// receives without a window.
+func (s *outputStreams) quiet(window time.Duration) <-chan time.Time {
+	if s == nil || window <= 0 {
+		return nil
+	}
+	return s.silence(window, time.Time{})
+}
+
+// silence waits for no output to have been read for window, counting from
+// since or, when since is zero, from the first output that is read.
+func (s *outputStreams) silence(window time.Duration, since time.Time) <-chan time.Time {
+	silent := make(chan time.Time, 1)
+	go func() {
+		timer := time.NewTimer(window)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-s.cancel:
+				return
+			}
+			last, ok := s.lastOutputTime()
+			if !ok && since.IsZero() {
+				timer.Reset(window)
+				continue
+			}
+			if !ok {
+				last = since
+			}
+			idle := time.Since(last)
+			if idle >= window {
+				silent <- last
+				return
+			}
+			timer.Reset(window - idle)
+		}
+	}()
+	return silent
+}
+
+// lastOutputTime returns when output was last read, if any has been.
+func (s *outputStreams) lastOutputTime() (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+	last := s.lastOutput.Load()
+	if last == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, last), true
+}
+
+// drain waits for the scanners to read the remaining output of a command that
+// has exited, then releases the streams.
+func (s *outputStreams) drain() {
+	if s == nil {
+		return
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		s.scanners.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(drainTimeout):
+	}
+	s.close()
+}
+
+// readErr returns the errors reading the output, once the streams are closed.
+func (s *outputStreams) readErr() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// firstOutput returns the time from start to the first output read from each
+// stream that wrote any, less the shell overhead, once the streams are closed.
+func (s *outputStreams) firstOutput(start time.Time, shellOverhead time.Duration) map[LineKind]time.Duration {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.first) == 0 {
+		return nil
+	}
+	first := make(map[LineKind]time.Duration, len(s.first))
+	for kind, at := range s.first {
+		first[kind] = max(at.Sub(start)-shellOverhead, 0)
+	}
+	return first
+}
+
+// close stops the scanners and waits for them to return.
+func (s *outputStreams) close() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		close(s.cancel)
+		for _, reader := range s.readers {
+			reader.Close()
+		}
+	})
+	s.scanners.Wait()
+}
+
+func (s *outputStreams) abort() {
+	if s == nil {
+		return
+	}
+	for _, writer := range s.writers {
+		writer.Close()
+	}
+	for _, reader := range s.readers {
+		reader.Close()
+	}
+}
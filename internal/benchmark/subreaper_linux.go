@@ -0,0 +1,59 @@
+package benchmark
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+const prSetChildSubreaper = 36
+
+// exitedGroups holds the process groups of commands that have been waited
+// for, whose remaining members are reaped by chrono as they exit.
+var exitedGroups = struct {
+	sync.Mutex
+	pgids map[int]struct{}
+}{pgids: make(map[int]struct{})}
+
+// becomeSubreaper makes orphaned descendants of chrono its own children rather
+// than init's, so that waitForProcessGroup can reap them itself instead of
+// waiting for init to notice them. Orphans that outlive their command are
+// reaped whenever a child exits.
+func becomeSubreaper() {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		return
+	}
+
+	children := make(chan os.Signal, 1)
+	signal.Notify(children, syscall.SIGCHLD)
+	go func() {
+		for range children {
+			reapExitedGroups()
+		}
+	}()
+}
+
+// releaseProcessGroup hands the rest of a command's process group to the
+// reaper. The command itself must already have been waited for, since any
+// member of the group may be reaped from now on.
+func releaseProcessGroup(pgid int) {
+	exitedGroups.Lock()
+	exitedGroups.pgids[pgid] = struct{}{}
+	exitedGroups.Unlock()
+	reapExitedGroups()
+}
+
+// reapExitedGroups reaps the members of released groups that have exited and
+// forgets the groups that have none left.
+func reapExitedGroups() {
+	exitedGroups.Lock()
+	defer exitedGroups.Unlock()
+	for pgid := range exitedGroups.pgids {
+		reapProcessGroup(pgid)
+		if err := syscall.Kill(-pgid, 0); errors.Is(err, syscall.ESRCH) {
+			delete(exitedGroups.pgids, pgid)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// zombieChildren lists the children of the test process that have exited but
+// not been reaped.
+func zombieChildren(t *testing.T) []int {
+	t.Helper()
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		t.Fatalf("Failed to list processes: %v", err)
+	}
+
+	var zombies []int
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// The command name is in parentheses and may contain spaces, so the
+		// fields are counted from the closing one.
+		fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+		if len(fields) < 2 || fields[0] != "Z" || fields[1] != strconv.Itoa(os.Getpid()) {
+			continue
+		}
+		pid, _ := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		zombies = append(zombies, pid)
+	}
+	return zombies
+}
+
+func TestOrphansReaped(t *testing.T) {
+	config := Config{
+		Command: []string{"sh", "-c", "(sleep 0.01 &); sleep 0.05"},
+		Prepare: "(sleep 0.05 &)",
+		Timeout: 5 * time.Second,
+	}
+	for range 5 {
+		if _, err := RunHook(config, HookPrepare); err != nil {
+			t.Fatalf("Expected the prepare hook to succeed, got %v", err)
+		}
+		if result := Run(config, 0); !result.Found {
+			t.Fatalf("Expected the run to succeed, got %+v", result)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		zombies := zombieChildren(t)
+		if len(zombies) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected every orphan to be reaped, found zombies %v", zombies)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build unix && !linux
+
+package benchmark
+
+func becomeSubreaper() {}
+
+func releaseProcessGroup(pgid int) {}
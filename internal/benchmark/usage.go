@@ -0,0 +1,84 @@
+package benchmark
+
+import (
+	"time"
+
+	"chrono/internal/stats"
+)
+
+// Usage is the resource usage the operating system reported for a run.
+type Usage struct {
+	UserTime            time.Duration
+	SystemTime          time.Duration
+	MaxRSS              int64 // bytes
+	MinorFaults         int64
+	MajorFaults         int64
+	VoluntarySwitches   int64
+	InvoluntarySwitches int64
+}
+
+// UsageStatistics summarises the resource usage of the runs that counted. It
+// reports false when none of them recorded any usage.
+func UsageStatistics(results []Result) (stats.UsageStatistics, bool) {
+	var user, system []time.Duration
+	var maxRSS, minorFaults, majorFaults, voluntary, involuntary []int64
+
+	for _, result := range results {
+		if !result.Found || result.Usage == nil {
+			continue
+		}
+		usage := result.Usage
+		user = append(user, usage.UserTime)
+		system = append(system, usage.SystemTime)
+		maxRSS = append(maxRSS, usage.MaxRSS)
+		minorFaults = append(minorFaults, usage.MinorFaults)
+		majorFaults = append(majorFaults, usage.MajorFaults)
+		voluntary = append(voluntary, usage.VoluntarySwitches)
+		involuntary = append(involuntary, usage.InvoluntarySwitches)
+	}
+
+	if len(user) == 0 {
+		return stats.UsageStatistics{}, false
+	}
+
+	return stats.UsageStatistics{
+		User:                stats.CalculateStatistics(user),
+		System:              stats.CalculateStatistics(system),
+		MaxRSS:              stats.CalculateCountStatistics(maxRSS),
+		MinorFaults:         stats.CalculateCountStatistics(minorFaults),
+		MajorFaults:         stats.CalculateCountStatistics(majorFaults),
+		VoluntarySwitches:   stats.CalculateCountStatistics(voluntary),
+		InvoluntarySwitches: stats.CalculateCountStatistics(involuntary),
+	}, true
+}
+
+// ShutdownStatistics summarises how long the counted runs that chrono had to
+// stop took to exit. It reports false when none of them were stopped.
+func ShutdownStatistics(results []Result) (stats.Statistics, bool) {
+	var shutdowns []time.Duration
+	for _, result := range results {
+		if result.Found && result.Shutdown > 0 {
+			shutdowns = append(shutdowns, result.Shutdown)
+		}
+	}
+	if len(shutdowns) == 0 {
+		return stats.Statistics{}, false
+	}
+	return stats.CalculateStatistics(shutdowns), true
+}
+
+// FirstOutputStatistics summarises the time to the first output on stream
+// across the counted runs. It reports false when none of them wrote to it, or
+// the output was not read by chrono.
+func FirstOutputStatistics(results []Result, stream LineKind) (stats.Statistics, bool) {
+	var times []time.Duration
+	for _, result := range results {
+		if elapsed, ok := result.FirstOutput[stream]; ok && result.Found {
+			times = append(times, elapsed)
+		}
+	}
+	if len(times) == 0 {
+		return stats.Statistics{}, false
+	}
+	return stats.CalculateStatistics(times), true
+}
@@ -1,7 +1,13 @@
 package output
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"chrono/internal/benchmark"
@@ -14,13 +20,35 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.3fs", d.Seconds())
 }
 
-func PrintCalibration(runs int) {
-	fmt.Printf("%s\n", colours.PurpleStyle.Render(fmt.Sprintf("Running %d calibration runs to measure shell startup overhead...", runs)))
+func FormatBytes(bytes float64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%.0f B", bytes)
+	}
+	value, exponent := bytes/unit, 0
+	for value >= unit && exponent < 3 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exponent])
+}
+
+// OverheadName names what calibration measures: shell startup when commands
+// run through a shell, and process spawning when they are executed directly.
+func OverheadName(config benchmark.Config) string {
+	if config.Shell != "" {
+		return "shell"
+	}
+	return "exec"
+}
+
+func PrintCalibration(config benchmark.Config) {
+	fmt.Printf("%s\n", colours.PurpleStyle.Render(fmt.Sprintf("Running %d calibration runs to measure %s startup overhead...", config.CalibrationRuns, OverheadName(config))))
 }
 
-func PrintShellOverhead(overhead time.Duration) {
+func PrintShellOverhead(config benchmark.Config, overhead time.Duration) {
 	fmt.Printf("%s %s\n\n",
-		colours.PurpleStyle.Render("Shell overhead:"),
+		colours.PurpleStyle.Render(fmt.Sprintf("%s overhead:", strings.ToUpper(OverheadName(config)[:1])+OverheadName(config)[1:])),
 		colours.BoldStyle.Render(FormatDuration(overhead)))
 }
 
@@ -30,9 +58,9 @@ func PrintWarmupHeader(warmups int) {
 
 func PrintWarmupResult(run int, result benchmark.Result) {
 	if !result.Found {
-		fmt.Printf("%s\n", colours.GrayStyle.Render(fmt.Sprintf("Warmup %d: phrase not found within timeout", run)))
+		fmt.Printf("%s\n", colours.GrayStyle.Render(fmt.Sprintf("Warmup %d: %s", run, result.Reason())))
 	} else {
-		fmt.Printf("%s\n", colours.GrayStyle.Render(fmt.Sprintf("Warmup %d: %s", run, FormatDuration(result.Duration))))
+		fmt.Printf("%s\n", colours.GrayStyle.Render(fmt.Sprintf("Warmup %d: %s%s", run, FormatDuration(result.Duration), ignoredFailure(result))))
 	}
 }
 
@@ -40,19 +68,128 @@ func PrintBenchmarkHeader(runs int) {
 	fmt.Printf("%s\n", colours.BlueStyle.Render(fmt.Sprintf("Running %d benchmark runs...", runs)))
 }
 
+func PrintAdaptiveHeader(config benchmark.Config) {
+	fmt.Printf("%s\n", colours.BlueStyle.Render(fmt.Sprintf("Running benchmark runs: %s...", config.RunsString())))
+}
+
+func PrintLoadHeader(config benchmark.Config) {
+	limit := fmt.Sprintf("%d benchmark runs", config.Runs)
+	if config.LoadDuration > 0 {
+		limit = fmt.Sprintf("benchmark runs for %s", config.LoadDuration)
+	}
+	fmt.Printf("%s\n", colours.BlueStyle.Render(fmt.Sprintf("Running %s, %d at a time...", limit, config.Concurrency)))
+}
+
+// PrintThroughput reports how many runs a concurrent load completed per second.
+func PrintThroughput(load benchmark.LoadResult, config benchmark.Config) {
+	fmt.Printf("%s %s\n",
+		colours.PurpleStyle.Render("Throughput:"),
+		fmt.Sprintf("%s runs/s (%d runs in %s at concurrency %d)",
+			colours.BoldStyle.Render(fmt.Sprintf("%.2f", load.Throughput())), len(load.Results), FormatDuration(load.Elapsed), config.Concurrency))
+}
+
+// PrintStopReason explains why a series with --runs auto ended.
+func PrintStopReason(reason benchmark.StopReason, results []benchmark.Result) {
+	precision := ""
+	if rse, ok := benchmark.Precision(results); ok {
+		precision = fmt.Sprintf(" (RSE %s)", benchmark.FormatRSE(rse))
+	}
+	fmt.Printf("%s\n", colours.GrayStyle.Render(fmt.Sprintf("Stopped after %d runs: %s%s", len(results), reason, precision)))
+}
+
 func PrintBenchmarkResult(run int, result benchmark.Result) {
 	headerStyle := colours.BoldStyle.Foreground(lipgloss.Color(colours.Cyan))
 	fmt.Printf("\n%s\n", headerStyle.Render(fmt.Sprintf("--- Benchmark Run %d ---", run)))
 
 	if !result.Found {
-		fmt.Printf("%s\n", colours.RedStyle.Render(fmt.Sprintf("Run %d: phrase not found within timeout", run)))
+		fmt.Printf("%s\n", colours.RedStyle.Render(fmt.Sprintf("Run %d: %s", run, result.Reason())))
 	} else {
-		fmt.Printf("%s\n", colours.GreenStyle.Render(fmt.Sprintf("Run %d: %s", run, colours.BoldStyle.Render(FormatDuration(result.Duration)))))
+		fmt.Printf("%s%s%s\n",
+			colours.GreenStyle.Render(fmt.Sprintf("Run %d: %s", run, colours.BoldStyle.Render(FormatDuration(result.Duration)))),
+			colours.YellowStyle.Render(ignoredFailure(result)+errorInfo(result)),
+			colours.GrayStyle.Render(streamInfo(result)+captureInfo(result)+shutdownInfo(result)))
+	}
+
+	if len(result.Checkpoints) > 0 {
+		checkpoints := make([]string, len(result.Checkpoints))
+		for i, checkpoint := range result.Checkpoints {
+			checkpoints[i] = fmt.Sprintf("%s %s", checkpoint.Name, FormatDuration(checkpoint.Elapsed))
+		}
+		fmt.Printf("%s\n", colours.GrayStyle.Render("  "+strings.Join(checkpoints, " → ")))
 	}
 }
-func PrintSummary(results []benchmark.Result, config benchmark.Config, shellOverhead time.Duration) {
-	fmt.Println()
 
+// errorInfo shows an error that did not stop a run from counting, such as
+// output that could not be read to the end.
+func errorInfo(result benchmark.Result) string {
+	if result.Err == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%v)", result.Err)
+}
+
+// streamInfo shows which stream the phrase was found on.
+func streamInfo(result benchmark.Result) string {
+	if result.Match == "" {
+		return ""
+	}
+	return fmt.Sprintf(" on %s", result.MatchStream)
+}
+
+func captureInfo(result benchmark.Result) string {
+	if len(result.Captures) == 0 {
+		return ""
+	}
+	names := slices.Sorted(maps.Keys(result.Captures))
+	captures := make([]string, len(names))
+	for i, name := range names {
+		captures[i] = name + "=" + result.Captures[name]
+	}
+	return fmt.Sprintf(" [%s]", strings.Join(captures, ", "))
+}
+
+func shutdownInfo(result benchmark.Result) string {
+	if result.Shutdown > 0 {
+		return fmt.Sprintf(" (shutdown %s)", FormatDuration(result.Shutdown))
+	}
+	return ""
+}
+
+func ignoredFailure(result benchmark.Result) string {
+	if reason := result.Reason(); reason != "" {
+		return fmt.Sprintf(" (%s, ignored)", reason)
+	}
+	return ""
+}
+
+func PrintCommandHeader(index, total int, config benchmark.Config) {
+	headerStyle := colours.BoldStyle.Foreground(lipgloss.Color(colours.Lavender))
+	parameterInfo := ""
+	if config.ParameterName != "" {
+		parameterInfo = colours.GrayStyle.Render(fmt.Sprintf(" (%s = %s)", config.ParameterName, config.ParameterValue))
+	}
+	fmt.Printf("%s %s%s\n",
+		headerStyle.Render(fmt.Sprintf("Benchmark %d/%d:", index, total)),
+		colours.BoldStyle.Render(config.CommandString()),
+		parameterInfo)
+}
+
+func PrintHookFailure(err error) {
+	fmt.Printf("%s\n", colours.RedStyle.Render(fmt.Sprintf("Error: %v", err)))
+
+	var hookErr *benchmark.HookError
+	if errors.As(err, &hookErr) && hookErr.Output != "" {
+		for line := range strings.SplitSeq(hookErr.Output, "\n") {
+			fmt.Printf("%s\n", colours.GrayStyle.Render(fmt.Sprintf("  %s: %s", hookErr.Hook, line)))
+		}
+	}
+}
+
+func PrintInterrupted(completed, total int) {
+	fmt.Printf("\n%s\n", colours.YellowStyle.Render(fmt.Sprintf("Interrupted after %d/%d runs", completed, total)))
+}
+
+func validDurations(results []benchmark.Result) ([]time.Duration, int) {
 	validResults := make([]time.Duration, 0, len(results))
 	failedCount := 0
 
@@ -64,6 +201,14 @@ func PrintSummary(results []benchmark.Result, config benchmark.Config, shellOver
 		}
 	}
 
+	return validResults, failedCount
+}
+
+func PrintSummary(results []benchmark.Result, config benchmark.Config, shellOverhead time.Duration) {
+	fmt.Println()
+
+	validResults, failedCount := validDurations(results)
+
 	warmupInfo := ""
 	if config.Warmups > 0 {
 		warmupInfo = fmt.Sprintf(" (%d warmups)", config.Warmups)
@@ -71,25 +216,39 @@ func PrintSummary(results []benchmark.Result, config benchmark.Config, shellOver
 
 	calibrationInfo := ""
 	if !config.SkipCalibration {
-		calibrationInfo = fmt.Sprintf(" (-%s shell overhead)", FormatDuration(shellOverhead))
+		calibrationInfo = fmt.Sprintf(" (-%s %s overhead)", FormatDuration(shellOverhead), OverheadName(config))
 	}
 
-	if config.Phrase == "" {
+	if config.HasProbe() {
+		fmt.Printf("%s%s\n",
+			colours.CyanStyle.Render("Probe:"),
+			fmt.Sprintf(" %s every %s%s%s", colours.BoldStyle.Render(config.Probe.String()), config.Probe.Interval, warmupInfo, calibrationInfo))
+	} else if config.UntilQuiet > 0 {
+		fmt.Printf("%s%s\n",
+			colours.CyanStyle.Render("Mode:"),
+			fmt.Sprintf(" Until output is quiet for %s%s%s", config.UntilQuiet, warmupInfo, calibrationInfo))
+	} else if !config.HasPhrase() {
 		fmt.Printf("%s%s\n",
 			colours.CyanStyle.Render("Mode:"),
 			fmt.Sprintf(" Command completion timing%s%s", warmupInfo, calibrationInfo))
 	} else {
 		fmt.Printf("%s%s\n",
 			colours.CyanStyle.Render("Phrase:"),
-			fmt.Sprintf(" \"%s\"%s%s", colours.BoldStyle.Render(config.Phrase), warmupInfo, calibrationInfo))
+			fmt.Sprintf(" %s%s%s", colours.BoldStyle.Render(config.PhraseString()), warmupInfo, calibrationInfo))
+	}
+	fmt.Printf("%s %s\n", colours.CyanStyle.Render("Output:"), config.OutputString())
+	if config.HasFailPhrases() {
+		fmt.Printf("%s %s\n", colours.CyanStyle.Render("Fail phrases:"), config.FailPhrasesString())
+	}
+	if env := config.EnvString(); env != "" {
+		fmt.Printf("%s %s\n", colours.CyanStyle.Render("Environment:"), env)
+	}
+	if config.Dir != "" {
+		fmt.Printf("%s %s\n", colours.CyanStyle.Render("Directory:"), config.Dir)
 	}
 
 	if len(validResults) == 0 {
-		if config.Phrase == "" {
-			fmt.Printf("%s\n", colours.RedStyle.Render("No successful runs - all commands timed out"))
-		} else {
-			fmt.Printf("%s\n", colours.RedStyle.Render("No successful runs - phrase was not found in any execution"))
-		}
+		fmt.Printf("%s\n", colours.RedStyle.Render("No successful runs - "+benchmark.DescribeFailures(results, config)))
 		return
 	}
 
@@ -101,6 +260,10 @@ func PrintSummary(results []benchmark.Result, config benchmark.Config, shellOver
 		fmt.Printf("%s %s\n",
 			colours.CyanStyle.Render("Time:"),
 			colours.BoldStyle.Render(FormatDuration(validResults[0])))
+		printFailureCauses(results)
+		printCheckpoints(results, config)
+		printFirstOutput(results)
+		printUsage(results)
 		return
 	}
 
@@ -113,4 +276,178 @@ func PrintSummary(results []benchmark.Result, config benchmark.Config, shellOver
 		colours.GreenStyle.Render("Min:"), FormatDuration(stats.Min),
 		colours.RedStyle.Render("Max:"), FormatDuration(stats.Max),
 		colours.YellowStyle.Render("Range:"), FormatDuration(stats.Range))
+	if config.Concurrency > 1 {
+		printLatency(validResults)
+	}
+	if rse, ok := benchmark.Precision(results); ok && config.AutoRuns {
+		fmt.Printf("%s %s (target %s)\n", colours.CyanStyle.Render("Precision:"), benchmark.FormatRSE(rse), benchmark.FormatRSE(config.TargetRSE))
+	}
+	printFailureCauses(results)
+	printCheckpoints(results, config)
+	printFirstOutput(results)
+	printUsage(results)
+}
+
+// printFailureCauses breaks the runs that did not count down by cause.
+func printFailureCauses(results []benchmark.Result) {
+	if causes := benchmark.FailureCauses(results); len(causes) > 0 {
+		fmt.Printf("%s %s\n", colours.RedStyle.Render("Failures:"), benchmark.FormatFailureCauses(causes))
+	}
+}
+
+// printLatency shows the distribution of run times under concurrent load.
+func printLatency(durations []time.Duration) {
+	fmt.Printf("%s p50 %s  p90 %s  p95 %s  p99 %s\n", colours.CyanStyle.Render("Latency:"),
+		FormatDuration(stats.Percentile(durations, 0.50)), FormatDuration(stats.Percentile(durations, 0.90)),
+		FormatDuration(stats.Percentile(durations, 0.95)), FormatDuration(stats.Percentile(durations, 0.99)))
+}
+
+func printCheckpoints(results []benchmark.Result, config benchmark.Config) {
+	summaries := benchmark.CheckpointStatistics(results, config)
+	if len(summaries) == 0 {
+		return
+	}
+
+	var table bytes.Buffer
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Checkpoint\tMean\tMin\tMax\tSplit")
+	for _, summary := range summaries {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t+%s\n", summary.Name,
+			FormatDuration(summary.Time.Mean), FormatDuration(summary.Time.Min),
+			FormatDuration(summary.Time.Max), FormatDuration(summary.Split.Mean))
+	}
+	writer.Flush()
+
+	for i, line := range strings.Split(strings.TrimRight(table.String(), "\n"), "\n") {
+		if i == 0 {
+			fmt.Printf("  %s\n", colours.CyanStyle.Render(line))
+			continue
+		}
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+// printFirstOutput shows how long the command took to write anything to each
+// stream.
+func printFirstOutput(results []benchmark.Result) {
+	var streams []string
+	for _, stream := range []benchmark.LineKind{benchmark.LineStdout, benchmark.LineStderr} {
+		if first, ok := benchmark.FirstOutputStatistics(results, stream); ok {
+			streams = append(streams, fmt.Sprintf("%s %s (%s … %s)", stream,
+				FormatDuration(first.Mean), FormatDuration(first.Min), FormatDuration(first.Max)))
+		}
+	}
+	if len(streams) > 0 {
+		fmt.Printf("%s %s\n", colours.CyanStyle.Render("First output:"), strings.Join(streams, "  "))
+	}
+}
+
+func printUsage(results []benchmark.Result) {
+	if shutdown, ok := benchmark.ShutdownStatistics(results); ok {
+		fmt.Printf("%s %s  %s %s  %s %s\n",
+			colours.CyanStyle.Render("Shutdown:"), FormatDuration(shutdown.Mean),
+			colours.GreenStyle.Render("Min:"), FormatDuration(shutdown.Min),
+			colours.RedStyle.Render("Max:"), FormatDuration(shutdown.Max))
+	}
+
+	usage, ok := benchmark.UsageStatistics(results)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("%s %s  %s %s  %s %s\n",
+		colours.CyanStyle.Render("User:"), FormatDuration(usage.User.Mean),
+		colours.CyanStyle.Render("System:"), FormatDuration(usage.System.Mean),
+		colours.CyanStyle.Render("Max RSS:"), FormatBytes(usage.MaxRSS.Mean))
+	fmt.Printf("%s %.0f minor, %.0f major  %s %.0f voluntary, %.0f involuntary\n",
+		colours.CyanStyle.Render("Page faults:"), usage.MinorFaults.Mean, usage.MajorFaults.Mean,
+		colours.CyanStyle.Render("Context switches:"), usage.VoluntarySwitches.Mean, usage.InvoluntarySwitches.Mean)
+}
+
+func PrintComparison(configs []benchmark.Config, results [][]benchmark.Result) {
+	names := make([]string, 0, len(configs))
+	statistics := make([]stats.Statistics, 0, len(configs))
+	var failed []string
+
+	for i, config := range configs {
+		validResults, _ := validDurations(results[i])
+		if len(validResults) == 0 {
+			failed = append(failed, config.CommandString())
+			continue
+		}
+		names = append(names, config.CommandString())
+		statistics = append(statistics, stats.CalculateStatistics(validResults))
+	}
+
+	fmt.Printf("\n%s\n", colours.BoldStyle.Render("Comparison"))
+
+	if len(statistics) == 0 {
+		fmt.Printf("%s\n", colours.RedStyle.Render("No successful runs to compare"))
+		return
+	}
+
+	ranked := stats.Rank(statistics)
+	fastest := ranked[0]
+	fmt.Printf("  %s ran\n", colours.CyanStyle.Render(names[fastest]))
+
+	for _, i := range ranked[1:] {
+		ratio, stdDev := stats.Ratio(statistics[i], statistics[fastest])
+		if ratio == 0 {
+			fmt.Printf("    %s %s\n", colours.GrayStyle.Render("cannot be compared with"), colours.CyanStyle.Render(names[i]))
+			continue
+		}
+		fmt.Printf("    %s times faster than %s\n",
+			colours.GreenStyle.Render(fmt.Sprintf("%.2f ± %.2f", ratio, stdDev)),
+			colours.CyanStyle.Render(names[i]))
+	}
+
+	for _, name := range failed {
+		fmt.Printf("  %s %s\n", colours.CyanStyle.Render(name), colours.RedStyle.Render("had no successful runs"))
+	}
+}
+
+func PrintParameterTable(configs []benchmark.Config, results [][]benchmark.Result) {
+	values := make(map[string]bool, len(configs))
+	showCommand := false
+	for _, config := range configs {
+		if values[config.ParameterValue] {
+			showCommand = true
+		}
+		values[config.ParameterValue] = true
+	}
+
+	var table bytes.Buffer
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	header := []string{configs[0].ParameterName, "Mean", "StdDev", "Min", "Max", "Runs"}
+	if showCommand {
+		header = append(header, "Command")
+	}
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+
+	for i, config := range configs {
+		validResults, _ := validDurations(results[i])
+		row := []string{config.ParameterValue, "-", "-", "-", "-"}
+		if len(validResults) > 0 {
+			stats := stats.CalculateStatistics(validResults)
+			row = []string{config.ParameterValue,
+				FormatDuration(stats.Mean), FormatDuration(stats.StdDev),
+				FormatDuration(stats.Min), FormatDuration(stats.Max)}
+		}
+		row = append(row, fmt.Sprintf("%d/%d", len(validResults), len(results[i])))
+		if showCommand {
+			row = append(row, config.CommandString())
+		}
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	writer.Flush()
+
+	fmt.Printf("\n%s\n", colours.BoldStyle.Render(fmt.Sprintf("Parameter scan: %s", configs[0].ParameterName)))
+	lines := strings.Split(strings.TrimRight(table.String(), "\n"), "\n")
+	for i, line := range lines {
+		if i == 0 {
+			fmt.Printf("  %s\n", colours.CyanStyle.Render(line))
+			continue
+		}
+		fmt.Printf("  %s\n", line)
+	}
 }
@@ -3,6 +3,7 @@ package output
 import (
 	"bytes"
 	"chrono/internal/benchmark"
+	"errors"
 	"io"
 	"os"
 	"strings"
@@ -44,12 +45,41 @@ func TestFormatDuration(t *testing.T) {
 	}
 }
 
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    float64
+		expected string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1536, "1.5 KiB"},
+		{10 * 1024 * 1024, "10.0 MiB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GiB"},
+	}
+
+	for _, test := range tests {
+		result := FormatBytes(test.bytes)
+		if result != test.expected {
+			t.Errorf("FormatBytes(%v) = %s, expected %s", test.bytes, result, test.expected)
+		}
+	}
+}
+
 func TestPrintCalibration(t *testing.T) {
 	output := captureOutput(func() {
-		PrintCalibration(5)
+		PrintCalibration(benchmark.Config{CalibrationRuns: 5, Shell: "/bin/sh"})
 	})
 
-	expected := "Running 5 calibration runs"
+	expected := "Running 5 calibration runs to measure shell startup overhead"
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain '%s', got '%s'", expected, output)
+	}
+
+	output = captureOutput(func() {
+		PrintCalibration(benchmark.Config{CalibrationRuns: 5})
+	})
+
+	expected = "exec startup overhead"
 	if !strings.Contains(output, expected) {
 		t.Errorf("Expected output to contain '%s', got '%s'", expected, output)
 	}
@@ -57,7 +87,7 @@ func TestPrintCalibration(t *testing.T) {
 
 func TestPrintShellOverhead(t *testing.T) {
 	output := captureOutput(func() {
-		PrintShellOverhead(50 * time.Millisecond)
+		PrintShellOverhead(benchmark.Config{Shell: "/bin/sh"}, 50*time.Millisecond)
 	})
 
 	expected := "Shell overhead:"
@@ -103,6 +133,7 @@ func TestPrintWarmupResult(t *testing.T) {
 		result := benchmark.Result{
 			Duration: 0,
 			Found:    false,
+			Status:   benchmark.StatusPhraseNotFound,
 		}
 
 		output := captureOutput(func() {
@@ -152,6 +183,7 @@ func TestPrintBenchmarkResult(t *testing.T) {
 		result := benchmark.Result{
 			Duration: 0,
 			Found:    false,
+			Status:   benchmark.StatusPhraseNotFound,
 		}
 
 		output := captureOutput(func() {
@@ -173,6 +205,7 @@ func TestPrintSummary(t *testing.T) {
 			Phrase:          "",
 			Warmups:         2,
 			SkipCalibration: false,
+			Shell:           "/bin/sh",
 		}
 		results := []benchmark.Result{
 			{Duration: 100 * time.Millisecond, Found: true},
@@ -225,7 +258,7 @@ func TestPrintSummary(t *testing.T) {
 			Phrase: "",
 		}
 		results := []benchmark.Result{
-			{Duration: 0, Found: false},
+			{Duration: 0, Found: false, Status: benchmark.StatusTimeout},
 		}
 
 		output := captureOutput(func() {
@@ -242,7 +275,7 @@ func TestPrintSummary(t *testing.T) {
 			Phrase: "notfound",
 		}
 		results := []benchmark.Result{
-			{Duration: 0, Found: false},
+			{Duration: 0, Found: false, Status: benchmark.StatusPhraseNotFound},
 		}
 
 		output := captureOutput(func() {
@@ -279,6 +312,52 @@ func TestPrintSummary(t *testing.T) {
 		}
 	})
 
+	t.Run("resource usage", func(t *testing.T) {
+		config := benchmark.Config{SkipCalibration: true}
+		results := []benchmark.Result{
+			{Duration: 100 * time.Millisecond, Found: true, Usage: &benchmark.Usage{
+				UserTime: 40 * time.Millisecond, SystemTime: 10 * time.Millisecond,
+				MaxRSS: 2 * 1024 * 1024, MinorFaults: 100, VoluntarySwitches: 4,
+			}},
+			{Duration: 200 * time.Millisecond, Found: true, Usage: &benchmark.Usage{
+				UserTime: 60 * time.Millisecond, SystemTime: 30 * time.Millisecond,
+				MaxRSS: 4 * 1024 * 1024, MinorFaults: 300, InvoluntarySwitches: 2,
+			}},
+			{Found: false, Status: benchmark.StatusTimeout, Usage: &benchmark.Usage{UserTime: time.Second}},
+		}
+
+		output := captureOutput(func() {
+			PrintSummary(results, config, 0)
+		})
+
+		for _, expected := range []string{"User: 0.050s", "System: 0.020s", "Max RSS: 3.0 MiB", "200 minor, 0 major", "2 voluntary, 1 involuntary"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got '%s'", expected, output)
+			}
+		}
+	})
+
+	t.Run("checkpoints", func(t *testing.T) {
+		config := benchmark.Config{
+			SkipCalibration: true,
+			Checkpoints:     []benchmark.Checkpoint{{Name: "db", Phrase: "db"}, {Name: "ready", Phrase: "ready"}},
+		}
+		results := []benchmark.Result{
+			{Duration: 300 * time.Millisecond, Found: true, Checkpoints: []benchmark.CheckpointTime{
+				{Name: "db", Elapsed: 100 * time.Millisecond}, {Name: "ready", Elapsed: 300 * time.Millisecond}}},
+			{Duration: 300 * time.Millisecond, Found: true, Checkpoints: []benchmark.CheckpointTime{
+				{Name: "db", Elapsed: 100 * time.Millisecond}, {Name: "ready", Elapsed: 300 * time.Millisecond}}},
+		}
+
+		output := captureOutput(func() {
+			PrintSummary(results, config, 0)
+		})
+
+		if !strings.Contains(output, "Checkpoint") || !strings.Contains(output, "+0.200s") {
+			t.Errorf("Expected checkpoint table with splits, got '%s'", output)
+		}
+	})
+
 	t.Run("all failed results", func(t *testing.T) {
 		config := benchmark.Config{
 			Phrase: "test",
@@ -297,3 +376,196 @@ func TestPrintSummary(t *testing.T) {
 		}
 	})
 }
+
+func TestPrintCommandHeader(t *testing.T) {
+	config := benchmark.Config{Command: []string{"echo", "hello world"}}
+
+	output := captureOutput(func() {
+		PrintCommandHeader(2, 3, config)
+	})
+
+	if !strings.Contains(output, "Benchmark 2/3:") {
+		t.Errorf("Expected output to contain 'Benchmark 2/3:', got '%s'", output)
+	}
+	if !strings.Contains(output, "echo 'hello world'") {
+		t.Errorf("Expected output to contain command, got '%s'", output)
+	}
+}
+
+func TestPrintComparison(t *testing.T) {
+	t.Run("ranked commands", func(t *testing.T) {
+		configs := []benchmark.Config{
+			{Command: []string{"slow"}},
+			{Command: []string{"fast"}},
+		}
+		results := [][]benchmark.Result{
+			{{Duration: 300 * time.Millisecond, Found: true}, {Duration: 300 * time.Millisecond, Found: true}},
+			{{Duration: 100 * time.Millisecond, Found: true}, {Duration: 100 * time.Millisecond, Found: true}},
+		}
+
+		output := captureOutput(func() {
+			PrintComparison(configs, results)
+		})
+
+		if !strings.Contains(output, "fast ran") {
+			t.Errorf("Expected fastest command first, got '%s'", output)
+		}
+		if !strings.Contains(output, "3.00 ± 0.00") {
+			t.Errorf("Expected relative speed, got '%s'", output)
+		}
+		if !strings.Contains(output, "times faster than slow") {
+			t.Errorf("Expected comparison with slower command, got '%s'", output)
+		}
+	})
+
+	t.Run("command without successful runs", func(t *testing.T) {
+		configs := []benchmark.Config{
+			{Command: []string{"ok"}},
+			{Command: []string{"broken"}},
+		}
+		results := [][]benchmark.Result{
+			{{Duration: 100 * time.Millisecond, Found: true}},
+			{{Found: false}},
+		}
+
+		output := captureOutput(func() {
+			PrintComparison(configs, results)
+		})
+
+		if !strings.Contains(output, "ok ran") {
+			t.Errorf("Expected successful command to be ranked, got '%s'", output)
+		}
+		if !strings.Contains(output, "broken had no successful runs") {
+			t.Errorf("Expected failed command to be reported, got '%s'", output)
+		}
+	})
+
+	t.Run("no successful runs", func(t *testing.T) {
+		configs := []benchmark.Config{{Command: []string{"a"}}, {Command: []string{"b"}}}
+		results := [][]benchmark.Result{{{Found: false}}, {{Found: false}}}
+
+		output := captureOutput(func() {
+			PrintComparison(configs, results)
+		})
+
+		if !strings.Contains(output, "No successful runs to compare") {
+			t.Errorf("Expected no comparison message, got '%s'", output)
+		}
+	})
+}
+
+func TestPrintParameterTable(t *testing.T) {
+	configs := []benchmark.Config{
+		{Command: []string{"sleep", "1"}, ParameterName: "n", ParameterValue: "1"},
+		{Command: []string{"sleep", "2"}, ParameterName: "n", ParameterValue: "2"},
+	}
+	results := [][]benchmark.Result{
+		{{Duration: 100 * time.Millisecond, Found: true}},
+		{{Found: false}},
+	}
+
+	output := captureOutput(func() {
+		PrintParameterTable(configs, results)
+	})
+
+	if !strings.Contains(output, "Parameter scan: n") {
+		t.Errorf("Expected table title, got '%s'", output)
+	}
+	if !strings.Contains(output, "0.100s") {
+		t.Errorf("Expected statistics for first value, got '%s'", output)
+	}
+	if !strings.Contains(output, "0/1") {
+		t.Errorf("Expected run count for failed value, got '%s'", output)
+	}
+	if strings.Contains(output, "Command") {
+		t.Errorf("Did not expect command column for a single command, got '%s'", output)
+	}
+}
+
+func TestPrintHookFailure(t *testing.T) {
+	err := &benchmark.HookError{
+		Hook:   benchmark.HookPrepare,
+		Err:    errors.New("exit status 1"),
+		Output: "line one\nline two",
+	}
+
+	output := captureOutput(func() {
+		PrintHookFailure(err)
+	})
+
+	if !strings.Contains(output, "prepare hook failed: exit status 1") {
+		t.Errorf("Expected hook failure message, got '%s'", output)
+	}
+	if !strings.Contains(output, "prepare: line two") {
+		t.Errorf("Expected labelled hook output, got '%s'", output)
+	}
+}
+
+func TestPrintResultReasons(t *testing.T) {
+	t.Run("non-zero exit", func(t *testing.T) {
+		result := benchmark.Result{Status: benchmark.StatusNonZeroExit, ExitCode: 2}
+
+		output := captureOutput(func() {
+			PrintBenchmarkResult(1, result)
+		})
+
+		if !strings.Contains(output, "Run 1: exited with code 2") {
+			t.Errorf("Expected exit code reason, got '%s'", output)
+		}
+	})
+
+	t.Run("ignored failure", func(t *testing.T) {
+		result := benchmark.Result{Duration: 100 * time.Millisecond, Found: true, Status: benchmark.StatusNonZeroExit, ExitCode: 1}
+
+		output := captureOutput(func() {
+			PrintBenchmarkResult(1, result)
+		})
+
+		if !strings.Contains(output, "0.100s") || !strings.Contains(output, "exited with code 1, ignored") {
+			t.Errorf("Expected duration with ignored failure, got '%s'", output)
+		}
+	})
+
+	t.Run("named captures", func(t *testing.T) {
+		result := benchmark.Result{Duration: 100 * time.Millisecond, Found: true, Match: "localhost:80",
+			Captures: map[string]string{"port": "80", "host": "localhost"}}
+
+		output := captureOutput(func() {
+			PrintBenchmarkResult(1, result)
+		})
+
+		if !strings.Contains(output, "[host=localhost, port=80]") {
+			t.Errorf("Expected sorted captures, got '%s'", output)
+		}
+	})
+
+	t.Run("summary of non-zero exits", func(t *testing.T) {
+		results := []benchmark.Result{
+			{Status: benchmark.StatusNonZeroExit, ExitCode: 1},
+			{Status: benchmark.StatusNonZeroExit, ExitCode: 1},
+		}
+
+		output := captureOutput(func() {
+			PrintSummary(results, benchmark.Config{}, 0)
+		})
+
+		if !strings.Contains(output, "non-zero code") {
+			t.Errorf("Expected non-zero exit summary, got '%s'", output)
+		}
+	})
+
+	t.Run("summary of mixed failures", func(t *testing.T) {
+		results := []benchmark.Result{
+			{Status: benchmark.StatusNonZeroExit, ExitCode: 1},
+			{Status: benchmark.StatusTimeout},
+		}
+
+		output := captureOutput(func() {
+			PrintSummary(results, benchmark.Config{}, 0)
+		})
+
+		if !strings.Contains(output, "all runs failed") {
+			t.Errorf("Expected mixed failure summary, got '%s'", output)
+		}
+	})
+}
@@ -2,52 +2,30 @@ package shellcalibration
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"time"
 
+	"chrono/internal/benchmark"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
-const fallbackShell = "/bin/sh"
-
-func CalibrateShellOverhead(runs int) time.Duration {
-	durations := make([]time.Duration, 0, runs)
-
-	calibrationShell := fallbackShell
-	calibrationArgs := []string{"-c", "true"}
-
-	if userShell := os.Getenv("SHELL"); userShell != "" {
-		calibrationShell = userShell
-	}
+// CalibrateShellOverhead measures how long it takes to start a command that
+// does nothing, through the same spawn path the benchmark uses: the configured
+// shell running `true`, or `true` executed directly when there is no shell.
+func CalibrateShellOverhead(config benchmark.Config) time.Duration {
+	durations := make([]time.Duration, 0, config.CalibrationRuns)
+	calibration := calibrationConfig(config)
 
 	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#f9e2af"))
-	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#f38ba8"))
-
-	for i := range runs {
-		start := time.Now()
-		cmd := exec.Command(calibrationShell, calibrationArgs...)
-		err := cmd.Run()
-		duration := time.Since(start)
 
-		if err != nil {
-			fmt.Printf("%s\n", warningStyle.Render(fmt.Sprintf("Calibration run %d failed with %s: %v", i+1, calibrationShell, err)))
-
-			if calibrationShell != fallbackShell {
-				start = time.Now()
-				cmd = exec.Command(fallbackShell, "-c", "true")
-				err = cmd.Run()
-				duration = time.Since(start)
-				if err != nil {
-					fmt.Printf("%s\n", errorStyle.Render(fmt.Sprintf("Calibration run %d failed completely: %v", i+1, err)))
-					continue
-				}
-			} else {
-				continue
-			}
+	for i := range config.CalibrationRuns {
+		result := benchmark.Run(calibration, 0)
+		if result.Status != benchmark.StatusSuccess {
+			fmt.Printf("%s\n", warningStyle.Render(fmt.Sprintf("Calibration run %d failed with %s: %s", i+1, calibration.CommandString(), result.Reason())))
+			continue
 		}
 
-		durations = append(durations, duration)
+		durations = append(durations, result.Duration)
 	}
 
 	if len(durations) == 0 {
@@ -62,3 +40,23 @@ func CalibrateShellOverhead(runs int) time.Duration {
 
 	return total / time.Duration(len(durations))
 }
+
+func calibrationConfig(config benchmark.Config) benchmark.Config {
+	calibration := benchmark.Config{
+		Command:    []string{"true"},
+		Shell:      config.Shell,
+		Env:        config.Env,
+		ClearEnv:   config.ClearEnv,
+		Dir:        config.Dir,
+		PTY:        config.PTY,
+		PTYColumns: config.PTYColumns,
+		PTYRows:    config.PTYRows,
+		Output:     config.Output,
+		KillSignal: config.KillSignal,
+	}
+	// `true` writes nothing, so there is no need to create output files.
+	if calibration.Output == benchmark.OutputFile {
+		calibration.Output = benchmark.OutputNull
+	}
+	return calibration
+}
@@ -2,20 +2,57 @@ package shellcalibration
 
 import (
 	"testing"
+
+	"chrono/internal/benchmark"
 )
 
 func TestCalibrateShellOverhead(t *testing.T) {
 	t.Run("shell calibration success", func(t *testing.T) {
-		overhead := CalibrateShellOverhead(3)
+		overhead := CalibrateShellOverhead(benchmark.Config{CalibrationRuns: 3, Shell: "/bin/sh"})
+		if overhead <= 0 {
+			t.Errorf("Expected positive overhead, got %v", overhead)
+		}
+	})
+
+	t.Run("direct exec calibration success", func(t *testing.T) {
+		overhead := CalibrateShellOverhead(benchmark.Config{CalibrationRuns: 3})
 		if overhead <= 0 {
 			t.Errorf("Expected positive overhead, got %v", overhead)
 		}
 	})
 
 	t.Run("minimal calibration runs", func(t *testing.T) {
-		overhead := CalibrateShellOverhead(1)
+		overhead := CalibrateShellOverhead(benchmark.Config{CalibrationRuns: 1})
 		if overhead < 0 {
 			t.Errorf("Expected non-negative overhead, got %v", overhead)
 		}
 	})
+
+	t.Run("failing shell", func(t *testing.T) {
+		overhead := CalibrateShellOverhead(benchmark.Config{CalibrationRuns: 2, Shell: "/nonexistent/shell"})
+		if overhead != 0 {
+			t.Errorf("Expected 0 overhead when calibration fails, got %v", overhead)
+		}
+	})
+}
+
+func TestCalibrationConfig(t *testing.T) {
+	config := calibrationConfig(benchmark.Config{
+		Command:  []string{"server"},
+		Shell:    "/bin/bash",
+		Phrase:   "ready",
+		Setup:    "make",
+		Env:      []string{"GOMAXPROCS=4"},
+		ClearEnv: true,
+		Dir:      "/tmp",
+	})
+	if config.Shell != "/bin/bash" || config.CommandString() != "true" {
+		t.Errorf("Expected calibration to run true through the same shell, got %+v", config)
+	}
+	if config.HasPhrase() || config.Setup != "" {
+		t.Errorf("Expected calibration to ignore phrase and hooks, got %+v", config)
+	}
+	if config.EnvString() != "cleared, GOMAXPROCS=4" || config.Dir != "/tmp" {
+		t.Errorf("Expected calibration to use the same environment and directory, got %+v", config)
+	}
 }
@@ -1,15 +1,18 @@
 package stats
 
 import (
+	"cmp"
+	"math"
 	"slices"
 	"time"
 )
 
 type Statistics struct {
-	Mean  time.Duration
-	Min   time.Duration
-	Max   time.Duration
-	Range time.Duration
+	Mean   time.Duration
+	Min    time.Duration
+	Max    time.Duration
+	Range  time.Duration
+	StdDev time.Duration
 }
 
 func CalculateStatistics(durations []time.Duration) Statistics {
@@ -29,10 +32,130 @@ func CalculateStatistics(durations []time.Duration) Statistics {
 	max := durations[len(durations)-1]
 	rang := max - min
 
+	// The sample standard deviation, as RelativeStandardError uses. A single
+	// duration has none.
+	var stdDev time.Duration
+	if len(durations) > 1 {
+		var variance float64
+		for _, d := range durations {
+			diff := d.Seconds() - mean.Seconds()
+			variance += diff * diff
+		}
+		variance /= float64(len(durations) - 1)
+		stdDev = time.Duration(math.Sqrt(variance) * float64(time.Second))
+	}
+
 	return Statistics{
-		Mean:  mean,
-		Min:   min,
-		Max:   max,
-		Range: rang,
+		Mean:   mean,
+		Min:    min,
+		Max:    max,
+		Range:  rang,
+		StdDev: stdDev,
+	}
+}
+
+// Percentile returns the duration below which the fraction p of durations
+// fall, interpolating between the nearest ranks.
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return sorted[lower] + time.Duration(weight*float64(sorted[upper]-sorted[lower]))
+}
+
+// RelativeStandardError is the standard error of the mean of durations as a
+// fraction of the mean, using the sample standard deviation. It needs at
+// least two durations and a positive mean.
+func RelativeStandardError(durations []time.Duration) (float64, bool) {
+	if len(durations) < 2 {
+		return 0, false
+	}
+
+	var total float64
+	for _, d := range durations {
+		total += d.Seconds()
+	}
+	n := float64(len(durations))
+	mean := total / n
+	if mean <= 0 {
+		return 0, false
+	}
+
+	var variance float64
+	for _, d := range durations {
+		diff := d.Seconds() - mean
+		variance += diff * diff
+	}
+	variance /= n - 1
+
+	return math.Sqrt(variance/n) / mean, true
+}
+
+// Ratio reports how many times slower a is than b, along with the standard
+// deviation of that ratio propagated from both sets of statistics.
+func Ratio(a, b Statistics) (float64, float64) {
+	if a.Mean <= 0 || b.Mean <= 0 {
+		return 0, 0
+	}
+
+	ratio := a.Mean.Seconds() / b.Mean.Seconds()
+	relA := a.StdDev.Seconds() / a.Mean.Seconds()
+	relB := b.StdDev.Seconds() / b.Mean.Seconds()
+
+	return ratio, ratio * math.Sqrt(relA*relA+relB*relB)
+}
+
+// Rank returns the indices of statistics ordered from the fastest mean to the
+// slowest.
+func Rank(statistics []Statistics) []int {
+	indices := make([]int, len(statistics))
+	for i := range indices {
+		indices[i] = i
 	}
+	slices.SortStableFunc(indices, func(a, b int) int {
+		return cmp.Compare(statistics[a].Mean, statistics[b].Mean)
+	})
+	return indices
+}
+
+type CountStatistics struct {
+	Mean float64
+	Min  int64
+	Max  int64
+}
+
+func CalculateCountStatistics(values []int64) CountStatistics {
+	if len(values) == 0 {
+		return CountStatistics{}
+	}
+
+	var total float64
+	for _, v := range values {
+		total += float64(v)
+	}
+
+	return CountStatistics{
+		Mean: total / float64(len(values)),
+		Min:  slices.Min(values),
+		Max:  slices.Max(values),
+	}
+}
+
+// UsageStatistics summarises the resources used by a set of runs.
+type UsageStatistics struct {
+	User                Statistics
+	System              Statistics
+	MaxRSS              CountStatistics
+	MinorFaults         CountStatistics
+	MajorFaults         CountStatistics
+	VoluntarySwitches   CountStatistics
+	InvoluntarySwitches CountStatistics
 }
@@ -1,6 +1,7 @@
 package stats
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -21,6 +22,9 @@ func TestCalculateStatistics(t *testing.T) {
 		if stats.Range != 0 {
 			t.Errorf("Expected range 0, got %v", stats.Range)
 		}
+		if stats.StdDev != 0 {
+			t.Errorf("Expected std dev 0, got %v", stats.StdDev)
+		}
 	})
 
 	t.Run("single value", func(t *testing.T) {
@@ -67,6 +71,11 @@ func TestCalculateStatistics(t *testing.T) {
 		if stats.Range != expectedRange {
 			t.Errorf("Expected range %v, got %v", expectedRange, stats.Range)
 		}
+
+		expectedStdDev := 100 * time.Millisecond
+		if diff := stats.StdDev - expectedStdDev; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("Expected std dev %v, got %v", expectedStdDev, stats.StdDev)
+		}
 	})
 
 	t.Run("unsorted values", func(t *testing.T) {
@@ -88,3 +97,121 @@ func TestCalculateStatistics(t *testing.T) {
 		}
 	})
 }
+
+func TestRatio(t *testing.T) {
+	t.Run("equal statistics", func(t *testing.T) {
+		a := Statistics{Mean: 100 * time.Millisecond}
+		ratio, stdDev := Ratio(a, a)
+
+		if ratio != 1 {
+			t.Errorf("Expected ratio 1, got %v", ratio)
+		}
+		if stdDev != 0 {
+			t.Errorf("Expected std dev 0, got %v", stdDev)
+		}
+	})
+
+	t.Run("slower command", func(t *testing.T) {
+		slow := Statistics{Mean: 300 * time.Millisecond, StdDev: 30 * time.Millisecond}
+		fast := Statistics{Mean: 100 * time.Millisecond, StdDev: 10 * time.Millisecond}
+		ratio, stdDev := Ratio(slow, fast)
+
+		if math.Abs(ratio-3) > 1e-9 {
+			t.Errorf("Expected ratio 3, got %v", ratio)
+		}
+		expected := 3 * math.Sqrt(0.01+0.01)
+		if math.Abs(stdDev-expected) > 1e-9 {
+			t.Errorf("Expected std dev %v, got %v", expected, stdDev)
+		}
+	})
+
+	t.Run("zero mean", func(t *testing.T) {
+		ratio, stdDev := Ratio(Statistics{Mean: time.Second}, Statistics{})
+
+		if ratio != 0 || stdDev != 0 {
+			t.Errorf("Expected zero ratio for zero mean, got %v ± %v", ratio, stdDev)
+		}
+	})
+}
+
+func TestRank(t *testing.T) {
+	statistics := []Statistics{
+		{Mean: 300 * time.Millisecond},
+		{Mean: 100 * time.Millisecond},
+		{Mean: 200 * time.Millisecond},
+		{Mean: 100 * time.Millisecond},
+	}
+
+	ranked := Rank(statistics)
+	expected := []int{1, 3, 2, 0}
+
+	if len(ranked) != len(expected) {
+		t.Fatalf("Expected %d indices, got %d", len(expected), len(ranked))
+	}
+	for i := range expected {
+		if ranked[i] != expected[i] {
+			t.Errorf("Expected rank %v, got %v", expected, ranked)
+			break
+		}
+	}
+}
+
+func TestCalculateCountStatistics(t *testing.T) {
+	t.Run("empty slice", func(t *testing.T) {
+		stats := CalculateCountStatistics(nil)
+		if stats != (CountStatistics{}) {
+			t.Errorf("Expected zero statistics, got %+v", stats)
+		}
+	})
+
+	t.Run("multiple values", func(t *testing.T) {
+		stats := CalculateCountStatistics([]int64{30, 10, 20, 40})
+		if stats.Mean != 25 {
+			t.Errorf("Expected mean 25, got %v", stats.Mean)
+		}
+		if stats.Min != 10 {
+			t.Errorf("Expected min 10, got %v", stats.Min)
+		}
+		if stats.Max != 40 {
+			t.Errorf("Expected max 40, got %v", stats.Max)
+		}
+	})
+}
+
+func TestRelativeStandardError(t *testing.T) {
+	if _, ok := RelativeStandardError([]time.Duration{time.Second}); ok {
+		t.Error("Expected no standard error for a single duration")
+	}
+
+	// Sample standard deviation of 1s, 2s and 3s is 1s, so the standard error
+	// of the 2s mean is 1/√3 s.
+	rse, ok := RelativeStandardError([]time.Duration{time.Second, 2 * time.Second, 3 * time.Second})
+	expected := 1 / math.Sqrt(3) / 2
+	if !ok || math.Abs(rse-expected) > 1e-9 {
+		t.Errorf("Expected RSE %v, got %v (%v)", expected, rse, ok)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{4 * time.Second, 1 * time.Second, 3 * time.Second, 2 * time.Second, 5 * time.Second}
+	tests := []struct {
+		p        float64
+		expected time.Duration
+	}{
+		{0, time.Second},
+		{0.5, 3 * time.Second},
+		{0.9, 4600 * time.Millisecond},
+		{1, 5 * time.Second},
+	}
+	for _, test := range tests {
+		if got := Percentile(durations, test.p); got != test.expected {
+			t.Errorf("Expected p%v to be %v, got %v", test.p*100, test.expected, got)
+		}
+	}
+	if durations[0] != 4*time.Second {
+		t.Error("Expected Percentile not to reorder its input")
+	}
+	if got := Percentile(nil, 0.5); got != 0 {
+		t.Errorf("Expected 0 for no durations, got %v", got)
+	}
+}
@@ -2,11 +2,10 @@ package tui
 
 import (
 	"fmt"
-	"math"
 	"os"
 	"strings"
-	"time"
 
+	"chrono/internal/benchmark"
 	"chrono/internal/stats"
 
 	"github.com/aymanbagabas/go-osc52/v2"
@@ -35,47 +34,128 @@ func (m Model) copyResultsToClipboard() tea.Cmd {
 }
 
 func (m Model) buildResultsString() string {
+	if m.config.ParameterName != "" {
+		return m.buildParameterTableString()
+	}
 
 	var results strings.Builder
 
-	cmd := m.formatCommandDisplay()
-	results.WriteString(cmd)
-	results.WriteString("\n")
+	for i, command := range m.commands {
+		if i > 0 {
+			results.WriteString("\n\n")
+		}
 
-	validResults, failedCount := m.filterValidResults()
+		cmd := m.formatCommandDisplay(i)
+		results.WriteString(cmd)
+		results.WriteString("\n")
+		if env := command.config.EnvString(); env != "" {
+			results.WriteString(fmt.Sprintf("Environment: %s\n", env))
+		}
+		if command.config.Dir != "" {
+			results.WriteString(fmt.Sprintf("Directory: %s\n", command.config.Dir))
+		}
+
+		validResults, failedCount := filterValidResults(command.benchmarkResults)
+
+		if command.hookErr != nil {
+			results.WriteString(fmt.Sprintf("Error: %v\n", command.hookErr))
+		}
 
-	if len(validResults) == 0 {
-		if m.config.Phrase == "" {
-			results.WriteString("No successful runs - all executions timed out")
+		if len(validResults) == 0 {
+			if command.hookErr != nil {
+				results.WriteString("No successful runs")
+			} else {
+				results.WriteString("No successful runs - " + benchmark.DescribeFailures(command.benchmarkResults, command.config))
+			}
+		} else if len(validResults) == 1 {
+			results.WriteString(fmt.Sprintf("Time: %s", formatDuration(validResults[0])))
+			if failedCount > 0 {
+				results.WriteString(fmt.Sprintf(" (%d failed)", failedCount))
+			}
 		} else {
-			results.WriteString("No successful runs - phrase not found")
+			stats := stats.CalculateStatistics(validResults)
+
+			results.WriteString(fmt.Sprintf("Mean: %s ± %s",
+				formatDuration(stats.Mean), formatDuration(stats.StdDev)))
+
+			if failedCount > 0 {
+				results.WriteString(fmt.Sprintf(" (%d/%d completed)", len(validResults), len(command.benchmarkResults)))
+			}
+
+			results.WriteString(fmt.Sprintf("\nRange: %s … %s",
+				formatDuration(stats.Min), formatDuration(stats.Max)))
+		}
+
+		for _, summary := range benchmark.CheckpointStatistics(command.benchmarkResults, command.config) {
+			results.WriteString(fmt.Sprintf("\nCheckpoint %s: %s ± %s (split %s)", summary.Name,
+				formatDuration(summary.Time.Mean), formatDuration(summary.Time.StdDev), formatDuration(summary.Split.Mean)))
+		}
+
+		for _, stream := range []benchmark.LineKind{benchmark.LineStdout, benchmark.LineStderr} {
+			if first, ok := benchmark.FirstOutputStatistics(command.benchmarkResults, stream); ok {
+				results.WriteString(fmt.Sprintf("\nFirst %s: %s … %s (mean %s)", stream,
+					formatDuration(first.Min), formatDuration(first.Max), formatDuration(first.Mean)))
+			}
 		}
-	} else if len(validResults) == 1 {
-		results.WriteString(fmt.Sprintf("Time: %s", formatDuration(validResults[0])))
-		if failedCount > 0 {
-			results.WriteString(fmt.Sprintf(" (%d failed)", failedCount))
+
+		if shutdown, ok := benchmark.ShutdownStatistics(command.benchmarkResults); ok {
+			results.WriteString(fmt.Sprintf("\nShutdown: %s … %s (mean %s)",
+				formatDuration(shutdown.Min), formatDuration(shutdown.Max), formatDuration(shutdown.Mean)))
 		}
-	} else {
-		stats := stats.CalculateStatistics(validResults)
-
-		var variance float64
-		meanSeconds := stats.Mean.Seconds()
-		for _, d := range validResults {
-			diff := d.Seconds() - meanSeconds
-			variance += diff * diff
+
+		if usage, ok := benchmark.UsageStatistics(command.benchmarkResults); ok {
+			results.WriteString(fmt.Sprintf("\nCPU: %s user, %s system  Max RSS: %s",
+				formatDuration(usage.User.Mean), formatDuration(usage.System.Mean), formatBytes(usage.MaxRSS.Mean)))
+			results.WriteString(fmt.Sprintf("\nPage faults: %.0f minor, %.0f major  Context switches: %.0f voluntary, %.0f involuntary",
+				usage.MinorFaults.Mean, usage.MajorFaults.Mean, usage.VoluntarySwitches.Mean, usage.InvoluntarySwitches.Mean))
 		}
-		variance /= float64(len(validResults))
-		stdDev := time.Duration(math.Sqrt(variance) * float64(time.Second))
 
-		results.WriteString(fmt.Sprintf("Mean: %s ± %s",
-			formatDuration(stats.Mean), formatDuration(stdDev)))
+		if len(validResults) > 0 {
+			for j, result := range command.benchmarkResults {
+				if !result.Found {
+					results.WriteString(fmt.Sprintf("\nRun %d: %s", j+1, result.Reason()))
+				}
+			}
+		}
+	}
+
+	if len(m.commands) > 1 {
+		ranked, statistics := m.rankCommands()
+		if len(ranked) > 0 {
+			results.WriteString(fmt.Sprintf("\n\n%s ran", m.commands[ranked[0]].config.CommandString()))
+			for i := 1; i < len(ranked); i++ {
+				ratio, stdDev := stats.Ratio(statistics[i], statistics[0])
+				if ratio == 0 {
+					continue
+				}
+				results.WriteString(fmt.Sprintf("\n  %.2f ± %.2f times faster than %s",
+					ratio, stdDev, m.commands[ranked[i]].config.CommandString()))
+			}
+		}
+	}
+
+	return results.String()
+}
+
+func (m Model) buildParameterTableString() string {
+	var results strings.Builder
 
-		if failedCount > 0 {
-			results.WriteString(fmt.Sprintf(" (%d/%d completed)", len(validResults), len(m.benchmarkResults)))
+	results.WriteString(strings.Join([]string{m.config.ParameterName, "mean", "stddev", "min", "max", "runs", "command"}, "\t"))
+	for _, command := range m.commands {
+		validResults, _ := filterValidResults(command.benchmarkResults)
+		row := []string{command.config.ParameterValue, "", "", "", ""}
+		if len(validResults) > 0 {
+			stats := stats.CalculateStatistics(validResults)
+			row = []string{command.config.ParameterValue,
+				formatDuration(stats.Mean), formatDuration(stats.StdDev),
+				formatDuration(stats.Min), formatDuration(stats.Max)}
 		}
+		row = append(row,
+			fmt.Sprintf("%d/%d", len(validResults), len(command.benchmarkResults)),
+			command.config.CommandString())
 
-		results.WriteString(fmt.Sprintf("\nRange: %s … %s",
-			formatDuration(stats.Min), formatDuration(stats.Max)))
+		results.WriteString("\n")
+		results.WriteString(strings.Join(row, "\t"))
 	}
 
 	return results.String()
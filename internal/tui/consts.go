@@ -13,7 +13,6 @@ const (
 
 const (
 	TickInterval          = 100 * time.Millisecond
-	StreamTickInterval    = 50 * time.Millisecond
 	ClipboardFeedbackTime = 3 * time.Second
 )
 
@@ -15,12 +15,19 @@ type runStartMsg struct {
 
 type runCompleteMsg struct {
 	result   benchmark.Result
+	err      error
+	isWarmup bool
+}
+
+type hookCompleteMsg struct {
+	hook     benchmark.Hook
+	output   string
+	err      error
 	isWarmup bool
-	output   []string
 }
 
 type outputLineMsg struct {
-	line string
+	line outputLine
 }
 
 type tickMsg time.Time
@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"chrono/internal/benchmark"
@@ -15,7 +17,19 @@ const (
 	StateCompleted
 )
 
+type commandResults struct {
+	config           benchmark.Config
+	warmupResults    []benchmark.Result
+	benchmarkResults []benchmark.Result
+	hookErr          error
+	runErr           error
+	stopReason       benchmark.StopReason
+}
+
 type Model struct {
+	ctx  context.Context
+	runs *sync.WaitGroup
+
 	config        benchmark.Config
 	state         int
 	shellOverhead time.Duration
@@ -23,17 +37,19 @@ type Model struct {
 	warmupProgress    int
 	benchmarkProgress int
 
-	warmupResults    []benchmark.Result
-	benchmarkResults []benchmark.Result
+	commands []commandResults
+	current  int
+	selected int
 
 	currentRun int
 	totalRuns  int
 
 	currentRunStartTime time.Time
+	seriesStartTime     time.Time
 	elapsedTime         time.Duration
 	isRunning           bool
 
-	commandOutput []string
+	commandOutput []outputLine
 	scrollOffset  int
 
 	width  int
@@ -45,30 +61,37 @@ type Model struct {
 	err error
 }
 
-func NewModel(config benchmark.Config) Model {
-	totalRuns := config.Warmups + config.Runs
-	return Model{
-		config:           config,
-		state:            StateCalibrating,
-		warmupResults:    make([]benchmark.Result, 0, config.Warmups),
-		benchmarkResults: make([]benchmark.Result, 0, config.Runs),
-		totalRuns:        totalRuns,
-		commandOutput:    make([]string, 0),
-		width:            DefaultWidth,
-		height:           DefaultHeight,
+func NewModel(ctx context.Context, configs []benchmark.Config) Model {
+	commands := make([]commandResults, len(configs))
+	totalRuns := 0
+	for i, config := range configs {
+		commands[i] = commandResults{
+			config:           config,
+			warmupResults:    make([]benchmark.Result, 0, config.Warmups),
+			benchmarkResults: make([]benchmark.Result, 0, config.Runs),
+		}
+		totalRuns += config.Warmups + config.Runs
+	}
+
+	m := Model{
+		ctx:           ctx,
+		runs:          &sync.WaitGroup{},
+		config:        configs[0],
+		state:         StateCalibrating,
+		commands:      commands,
+		totalRuns:     totalRuns,
+		commandOutput: make([]outputLine, 0),
+		width:         DefaultWidth,
+		height:        DefaultHeight,
 	}
+	m.commandOutput = m.appendCommandHeader(m.commandOutput)
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
 	if m.config.SkipCalibration {
-		if m.config.Warmups > 0 {
-			return tea.Batch(
-				m.startWarmup(),
-				m.tickCmd(),
-			)
-		}
 		return tea.Batch(
-			m.startBenchmark(),
+			m.runHook(benchmark.HookSetup, false),
 			m.tickCmd(),
 		)
 	}
@@ -78,11 +101,16 @@ func (m Model) Init() tea.Cmd {
 	)
 }
 
-func Run(config benchmark.Config) error {
-	model := NewModel(config)
+func Run(configs []benchmark.Config) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	model := NewModel(ctx, configs)
 
 	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	_, err := p.Run()
+
+	// Quitting mid-run must not leave the benchmarked command running.
+	cancel()
+	model.runs.Wait()
 	return err
 }
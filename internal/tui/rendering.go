@@ -2,8 +2,10 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
+	"chrono/internal/benchmark"
 	"chrono/internal/colours"
 	"chrono/internal/stats"
 
@@ -11,91 +13,7 @@ import (
 )
 
 func (m Model) calculateLeftContentWidth() int {
-	maxWidth := 0
-
-	cmd := m.formatCommandDisplay()
-	if m.config.Phrase != "" {
-		cmd += fmt.Sprintf("\nPhrase: \"%s\"", m.config.Phrase)
-	}
-	for line := range strings.SplitSeq(cmd, "\n") {
-		if len(line) > maxWidth {
-			maxWidth = len(line)
-		}
-	}
-
-	var timeoutStr string
-	if m.config.Timeout > 0 {
-		timeoutStr = m.config.Timeout.String()
-	} else {
-		timeoutStr = "none"
-	}
-	configLines := []string{
-		fmt.Sprintf("Warmups: %d", m.config.Warmups),
-		fmt.Sprintf("Runs: %d", m.config.Runs),
-		fmt.Sprintf("Timeout: %s", timeoutStr),
-	}
-	if !m.config.SkipCalibration {
-		configLines = append(configLines, fmt.Sprintf("Shell overhead: %s", formatDuration(m.shellOverhead)))
-	}
-	for _, line := range configLines {
-		if len(line) > maxWidth {
-			maxWidth = len(line)
-		}
-	}
-
-	var statusLine string
-	switch m.state {
-	case StateCalibrating:
-		statusLine = "Status: Calibrating"
-	case StateWarmup:
-		statusLine = fmt.Sprintf("Status: Warmup (%d/%d)", m.warmupProgress, m.config.Warmups)
-	case StateBenchmarking:
-		statusLine = fmt.Sprintf("Status: Benchmarking (%d/%d)", m.benchmarkProgress+1, m.config.Runs)
-	case StateCompleted:
-		statusLine = "Status: Completed"
-	}
-	if len(statusLine) > maxWidth {
-		maxWidth = len(statusLine)
-	}
-
-	timingLines := []string{"Run Timings:"}
-	for i := range m.warmupResults {
-		line := fmt.Sprintf("  W%d: %s", i+1, formatDuration(m.warmupResults[i].Duration))
-		if !m.warmupResults[i].Found {
-			line = fmt.Sprintf("  W%d: timeout", i+1)
-		}
-		timingLines = append(timingLines, line)
-	}
-	for i := range m.benchmarkResults {
-		line := fmt.Sprintf("  #%d: %s", i+1, formatDuration(m.benchmarkResults[i].Duration))
-		if !m.benchmarkResults[i].Found {
-			line = fmt.Sprintf("  #%d: timeout", i+1)
-		}
-		timingLines = append(timingLines, line)
-	}
-
-	if m.state == StateCompleted {
-		summaryLines := []string{"Final Results:"}
-		validResults, _ := m.filterValidResults()
-		if len(validResults) > 1 {
-			stats := stats.CalculateStatistics(validResults)
-			summaryLines = append(summaryLines,
-				fmt.Sprintf("  Mean: %s", formatDuration(stats.Mean)),
-				fmt.Sprintf("  Min: %s", formatDuration(stats.Min)),
-				fmt.Sprintf("  Max: %s", formatDuration(stats.Max)),
-				fmt.Sprintf("  Range: %s", formatDuration(stats.Range)),
-			)
-		}
-		timingLines = append(timingLines, summaryLines...)
-	}
-
-	for _, line := range timingLines {
-		if len(line) > maxWidth {
-			maxWidth = len(line)
-		}
-	}
-
-	return maxWidth + 4
+	return lipgloss.Width(m.renderLeftColumnContentText()) + 4
 }
 
 func (m Model) renderLeftColumnContentText() string {
@@ -110,13 +28,22 @@ func (m Model) renderLeftColumnContentText() string {
 		return s.String()
 	}
 
+	command := m.commands[m.selected]
+	config := command.config
+
 	commandStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(colours.Blue)).
 		Bold(true)
 
-	cmd := m.formatCommandDisplay()
-	if m.config.Phrase != "" {
-		cmd += fmt.Sprintf("\nPhrase: \"%s\"", m.config.Phrase)
+	cmd := m.formatCommandDisplay(m.selected)
+	if config.HasPhrase() {
+		cmd += fmt.Sprintf("\nPhrase: %s", config.PhraseString())
+	}
+	if config.HasProbe() {
+		cmd += fmt.Sprintf("\nProbe: %s every %s", config.Probe, config.Probe.Interval)
+	}
+	if config.UntilQuiet > 0 {
+		cmd += fmt.Sprintf("\nUntil quiet: %s", config.UntilQuiet)
 	}
 	s.WriteString(commandStyle.Render(cmd))
 	s.WriteString("\n\n")
@@ -125,16 +52,35 @@ func (m Model) renderLeftColumnContentText() string {
 		Foreground(lipgloss.Color(colours.Subtext0))
 
 	var configInfo strings.Builder
-	configInfo.WriteString(fmt.Sprintf("Warmups: %d\n", m.config.Warmups))
-	configInfo.WriteString(fmt.Sprintf("Runs: %d\n", m.config.Runs))
-	if m.config.Timeout > 0 {
-		configInfo.WriteString(fmt.Sprintf("Timeout: %s\n", m.config.Timeout))
+	configInfo.WriteString(fmt.Sprintf("Warmups: %d\n", config.Warmups))
+	configInfo.WriteString(fmt.Sprintf("Runs: %s\n", config.RunsString()))
+	if config.Shell != "" {
+		configInfo.WriteString(fmt.Sprintf("Shell: %s\n", config.Shell))
+	}
+	if config.Stdin != benchmark.StdinNull {
+		configInfo.WriteString(fmt.Sprintf("Stdin: %s\n", config.StdinString()))
+	}
+	configInfo.WriteString(fmt.Sprintf("Output: %s\n", config.OutputString()))
+	if env := config.EnvString(); env != "" {
+		configInfo.WriteString(fmt.Sprintf("Environment: %s\n", env))
+	}
+	if config.Dir != "" {
+		configInfo.WriteString(fmt.Sprintf("Directory: %s\n", config.Dir))
+	}
+	if config.HasFailPhrases() {
+		configInfo.WriteString(fmt.Sprintf("Fail phrases: %s\n", config.FailPhrasesString()))
+	}
+	if config.Timeout > 0 {
+		configInfo.WriteString(fmt.Sprintf("Timeout: %s\n", config.Timeout))
 	} else {
 		configInfo.WriteString("Timeout: none\n")
 	}
+	if config.IdleTimeout > 0 {
+		configInfo.WriteString(fmt.Sprintf("Idle timeout: %s\n", config.IdleTimeout))
+	}
 
-	if !m.config.SkipCalibration {
-		configInfo.WriteString(fmt.Sprintf("Shell overhead: %s\n", formatDuration(m.shellOverhead)))
+	if !config.SkipCalibration {
+		configInfo.WriteString(fmt.Sprintf("%s overhead: %s\n", overheadLabel(config), formatDuration(m.shellOverhead)))
 	}
 
 	s.WriteString(configStyle.Render(configInfo.String()))
@@ -152,31 +98,40 @@ func (m Model) renderLeftColumnContentText() string {
 		status = fmt.Sprintf("Status: Warmup (%d/%d)", m.warmupProgress, m.config.Warmups)
 	case StateBenchmarking:
 		status = fmt.Sprintf("Status: Benchmarking (%d/%d)", m.benchmarkProgress+1, m.config.Runs)
+		if m.config.AutoRuns {
+			status += precisionStatus(m.commands[m.current])
+		}
 	case StateCompleted:
 		status = "Status: Completed"
+		if command.config.AutoRuns {
+			status += fmt.Sprintf(" (%s)", command.stopReason)
+		}
 	}
 	s.WriteString(statusStyle.Render(status))
 	s.WriteString("\n\n")
 
+	if len(m.commands) > 1 {
+		s.WriteString(m.renderCommandList())
+		s.WriteString("\n")
+	}
+
 	runTimingsStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(colours.Blue)).
 		Bold(true)
 	s.WriteString(runTimingsStyle.Render("Run Timings:"))
 	s.WriteString("\n")
 
+	isCurrent := m.selected == m.current
+
 	warmupStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(colours.Lavender))
 
-	for i, result := range m.warmupResults {
-		if result.Found {
-			s.WriteString(warmupStyle.Render(fmt.Sprintf("  W%d: %s", i+1, formatDuration(result.Duration))))
-		} else {
-			s.WriteString(warmupStyle.Render(fmt.Sprintf("  W%d: timeout", i+1)))
-		}
+	for i, result := range command.warmupResults {
+		s.WriteString(warmupStyle.Render(fmt.Sprintf("  W%d: %s", i+1, formatResult(result))))
 		s.WriteString("\n")
 	}
 
-	if m.isRunning && m.state == StateWarmup {
+	if m.isRunning && isCurrent && m.state == StateWarmup {
 		currentStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color(colours.Green))
 		s.WriteString(currentStyle.Render(fmt.Sprintf("  W%d: %s", m.warmupProgress+1, formatDuration(m.elapsedTime))))
@@ -186,34 +141,51 @@ func (m Model) renderLeftColumnContentText() string {
 	timingStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(colours.Text))
 
-	for i, result := range m.benchmarkResults {
-		if result.Found {
-			s.WriteString(timingStyle.Render(fmt.Sprintf("  #%d: %s", i+1, formatDuration(result.Duration))))
-		} else {
-			s.WriteString(timingStyle.Render(fmt.Sprintf("  #%d: timeout", i+1)))
+	failedTimingStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Red))
+
+	for i, result := range command.benchmarkResults {
+		style := timingStyle
+		if !result.Found {
+			style = failedTimingStyle
 		}
+		s.WriteString(style.Render(fmt.Sprintf("  #%d: %s", i+1, formatResult(result))))
 		s.WriteString("\n")
 	}
 
-	if m.isRunning && m.state == StateBenchmarking {
+	if m.isRunning && isCurrent && m.state == StateBenchmarking {
 		currentStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color(colours.Green))
 		s.WriteString(currentStyle.Render(fmt.Sprintf("  #%d: %s", m.benchmarkProgress+1, formatDuration(m.elapsedTime))))
 		s.WriteString("\n")
 	}
 
-	if m.state == StateCompleted {
+	if command.hookErr != nil {
+		hookErrorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(colours.Red)).
+			Bold(true)
+		s.WriteString("\n")
+		s.WriteString(hookErrorStyle.Render(fmt.Sprintf("Error: %v", command.hookErr)))
 		s.WriteString("\n")
-		validResults, failedCount := m.filterValidResults()
+	}
+
+	if command.runErr != nil {
+		runErrorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(colours.Red)).
+			Bold(true)
+		s.WriteString("\n")
+		s.WriteString(runErrorStyle.Render(fmt.Sprintf("Error: %v", command.runErr)))
+		s.WriteString("\n")
+	}
+
+	if m.commandCompleted(m.selected) && (command.hookErr == nil || len(command.benchmarkResults) > 0) {
+		s.WriteString("\n")
+		validResults, failedCount := filterValidResults(command.benchmarkResults)
 
 		if len(validResults) == 0 {
 			errorStyle := lipgloss.NewStyle().
 				Foreground(lipgloss.Color(colours.Red))
-			if m.config.Phrase == "" {
-				s.WriteString(errorStyle.Render("No successful runs - all commands timed out"))
-			} else {
-				s.WriteString(errorStyle.Render("No successful runs - phrase was not found"))
-			}
+			s.WriteString(errorStyle.Render("No successful runs - " + benchmark.DescribeFailures(command.benchmarkResults, config)))
 		} else {
 			summaryStyle := lipgloss.NewStyle().
 				Foreground(lipgloss.Color(colours.Green)).
@@ -224,7 +196,9 @@ func (m Model) renderLeftColumnContentText() string {
 			if failedCount > 0 {
 				failedStyle := lipgloss.NewStyle().
 					Foreground(lipgloss.Color(colours.Red))
-				s.WriteString(failedStyle.Render(fmt.Sprintf("  Failed: %d/%d", failedCount, len(m.benchmarkResults))))
+				s.WriteString(failedStyle.Render(fmt.Sprintf("  Failed: %d/%d", failedCount, len(command.benchmarkResults))))
+				s.WriteString("\n")
+				s.WriteString(failedStyle.Render("  " + benchmark.FormatFailureCauses(benchmark.FailureCauses(command.benchmarkResults))))
 				s.WriteString("\n")
 			}
 
@@ -237,6 +211,128 @@ func (m Model) renderLeftColumnContentText() string {
 				s.WriteString(fmt.Sprintf("  Max: %s\n", formatDuration(stats.Max)))
 				s.WriteString(fmt.Sprintf("  Range: %s", formatDuration(stats.Range)))
 			}
+
+			if rse, ok := benchmark.Precision(command.benchmarkResults); ok && config.AutoRuns {
+				s.WriteString(fmt.Sprintf("\n  RSE: %s (%s)", benchmark.FormatRSE(rse), command.stopReason))
+			}
+
+			for _, stream := range []benchmark.LineKind{benchmark.LineStdout, benchmark.LineStderr} {
+				if first, ok := benchmark.FirstOutputStatistics(command.benchmarkResults, stream); ok {
+					s.WriteString(fmt.Sprintf("\n  First %s: %s", stream, formatDuration(first.Mean)))
+				}
+			}
+
+			if shutdown, ok := benchmark.ShutdownStatistics(command.benchmarkResults); ok {
+				s.WriteString(fmt.Sprintf("\n  Shutdown: %s", formatDuration(shutdown.Mean)))
+			}
+
+			if usage, ok := benchmark.UsageStatistics(command.benchmarkResults); ok {
+				s.WriteString(fmt.Sprintf("\n  User: %s\n", formatDuration(usage.User.Mean)))
+				s.WriteString(fmt.Sprintf("  System: %s\n", formatDuration(usage.System.Mean)))
+				s.WriteString(fmt.Sprintf("  Max RSS: %s\n", formatBytes(usage.MaxRSS.Mean)))
+				s.WriteString(fmt.Sprintf("  Page faults: %.0f minor, %.0f major\n", usage.MinorFaults.Mean, usage.MajorFaults.Mean))
+				s.WriteString(fmt.Sprintf("  Switches: %.0f vol, %.0f invol", usage.VoluntarySwitches.Mean, usage.InvoluntarySwitches.Mean))
+			}
+
+			if summaries := benchmark.CheckpointStatistics(command.benchmarkResults, config); len(summaries) > 0 {
+				s.WriteString("\n\n")
+				s.WriteString(summaryStyle.Render("Checkpoints:"))
+				for _, summary := range summaries {
+					s.WriteString(fmt.Sprintf("\n  %s: %s (+%s)", summary.Name,
+						formatDuration(summary.Time.Mean), formatDuration(summary.Split.Mean)))
+				}
+			}
+		}
+	}
+
+	if m.state == StateCompleted && len(m.commands) > 1 && m.config.ParameterName == "" {
+		s.WriteString("\n\n")
+		s.WriteString(m.renderComparison())
+	}
+
+	return s.String()
+}
+
+func (m Model) renderCommandList() string {
+	var s strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Blue)).
+		Bold(true)
+	s.WriteString(headerStyle.Render("Commands:"))
+	s.WriteString("\n")
+
+	itemStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Subtext0))
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Text)).
+		Bold(true)
+
+	for i, command := range m.commands {
+		var progress string
+		switch {
+		case m.commandCompleted(i):
+			validResults, _ := filterValidResults(command.benchmarkResults)
+			if len(validResults) == 0 {
+				progress = "failed"
+			} else {
+				stats := stats.CalculateStatistics(validResults)
+				progress = fmt.Sprintf("%s ± %s", formatDuration(stats.Mean), formatDuration(stats.StdDev))
+			}
+		case i == m.current:
+			progress = "running"
+		default:
+			progress = "pending"
+		}
+
+		marker := " "
+		style := itemStyle
+		if i == m.selected {
+			marker = ">"
+			style = selectedStyle
+		}
+		label := command.config.CommandString()
+		if command.config.ParameterName != "" {
+			label = fmt.Sprintf("%s = %s", command.config.ParameterName, command.config.ParameterValue)
+		}
+		s.WriteString(style.Render(fmt.Sprintf("%s %d. %s: %s", marker, i+1, label, progress)))
+		s.WriteString("\n")
+	}
+
+	return s.String()
+}
+
+func (m Model) renderComparison() string {
+	var s strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Green)).
+		Bold(true)
+	s.WriteString(headerStyle.Render("Comparison:"))
+	s.WriteString("\n")
+
+	ranked, statistics := m.rankCommands()
+	if len(ranked) == 0 {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(colours.Red))
+		s.WriteString(errorStyle.Render("  No successful runs to compare"))
+		return s.String()
+	}
+
+	s.WriteString(fmt.Sprintf("  %s ran", m.commands[ranked[0]].config.CommandString()))
+	for i := 1; i < len(ranked); i++ {
+		ratio, stdDev := stats.Ratio(statistics[i], statistics[0])
+		name := m.commands[ranked[i]].config.CommandString()
+		if ratio == 0 {
+			s.WriteString(fmt.Sprintf("\n    cannot be compared with %s", name))
+			continue
+		}
+		s.WriteString(fmt.Sprintf("\n    %.2f ± %.2f times faster than %s", ratio, stdDev, name))
+	}
+
+	for i, command := range m.commands {
+		if !slices.Contains(ranked, i) {
+			s.WriteString(fmt.Sprintf("\n  %s had no successful runs", command.config.CommandString()))
 		}
 	}
 
@@ -255,6 +351,10 @@ func (m Model) renderRightColumnContentText(maxWidth, maxHeight int) string {
 		Foreground(lipgloss.Color(colours.Yellow)).
 		Bold(true)
 
+	commandHeaderStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Lavender)).
+		Bold(true)
+
 	matchStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(colours.Green)).
 		Background(lipgloss.Color(colours.Surface0)).
@@ -263,6 +363,13 @@ func (m Model) renderRightColumnContentText(maxWidth, maxHeight int) string {
 	regularStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(colours.Text))
 
+	hookStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Cyan))
+
+	hookFailureStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colours.Red)).
+		Bold(true)
+
 	availableLines := maxHeight - 2
 
 	startIdx := m.scrollOffset
@@ -270,17 +377,24 @@ func (m Model) renderRightColumnContentText(maxWidth, maxHeight int) string {
 
 	linesRendered := 0
 	for i := startIdx; i < endIdx && linesRendered < availableLines; i++ {
-		line := m.commandOutput[i]
+		line := m.commandOutput[i].text
 
 		if lipgloss.Width(line) > maxWidth {
 			line = truncateToWidth(line, maxWidth)
 		}
 
-		if strings.HasPrefix(line, "--- Benchmark Run") {
+		switch m.commandOutput[i].kind {
+		case outputSeparator:
 			s.WriteString(separatorStyle.Render(line))
-		} else if strings.Contains(line, "Match found!") {
+		case outputCommandHeader:
+			s.WriteString(commandHeaderStyle.Render(line))
+		case outputHook:
+			s.WriteString(hookStyle.Render(line))
+		case outputHookFailure, outputFailure:
+			s.WriteString(hookFailureStyle.Render(line))
+		case outputMatch:
 			s.WriteString(matchStyle.Render(line))
-		} else {
+		default:
 			s.WriteString(regularStyle.Render(line))
 		}
 		s.WriteString("\n")
@@ -1,253 +1,112 @@
 package tui
 
 import (
+	"strings"
+	"unicode"
+
 	"chrono/internal/benchmark"
-	"io"
-	"os/exec"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 )
 
-type startStreamingMsg struct {
-	cmd         *exec.Cmd
-	stdout      io.ReadCloser
-	stderr      io.ReadCloser
-	startTime   time.Time
-	isWarmup    bool
-	phrase      string
-	done        <-chan time.Duration
-	cancel      chan struct{}
-	outputLines <-chan string
-}
-
 type streamNextMsg struct {
-	cmd                *exec.Cmd
-	startTime          time.Time
-	isWarmup           bool
-	phrase             string
-	done               <-chan time.Duration
-	cancel             chan struct{}
-	outputLines        <-chan string
-	matchFoundReceived bool
-	phraseMatchResult  *benchmark.Result
-	commandCompleted   bool
-	completionResult   *benchmark.Result
+	isWarmup bool
+	lines    <-chan benchmark.Line
+	done     <-chan runCompleteMsg
 }
 
 type newOutputLineMsg struct {
-	line       string
+	lines      []outputLine
 	streamNext streamNextMsg
 }
 
-func (m Model) startStreaming(msg startStreamingMsg) tea.Cmd {
-	return tea.Cmd(func() tea.Msg {
-		return streamNextMsg{
-			cmd:                msg.cmd,
-			startTime:          msg.startTime,
-			isWarmup:           msg.isWarmup,
-			phrase:             msg.phrase,
-			done:               msg.done,
-			cancel:             msg.cancel,
-			outputLines:        msg.outputLines,
-			matchFoundReceived: false,
-			phraseMatchResult:  nil,
-			commandCompleted:   false,
-			completionResult:   nil,
-		}
-	})
-}
-
-func (m Model) handleStreamNext(msg streamNextMsg) tea.Cmd {
-	return func() tea.Msg {
-		if m.config.Timeout > 0 {
-			select {
-			case duration := <-msg.done:
-				if msg.cancel != nil {
-					result := m.createAdjustedResult(duration, true)
-
-					updatedMsg := msg
-					updatedMsg.phraseMatchResult = &result
-
-					return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-						return updatedMsg
-					})()
-				} else {
-					result := m.createAdjustedResult(duration, true)
-
-					updatedMsg := msg
-					updatedMsg.commandCompleted = true
-					updatedMsg.completionResult = &result
-
-					return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-						return updatedMsg
-					})()
-				}
-
-			case <-time.After(m.config.Timeout):
-				if msg.cancel != nil {
-					close(msg.cancel)
-				}
-				if msg.cmd.Process != nil {
-					msg.cmd.Process.Kill()
-				}
-
-				result := m.createTimeoutResult()
-
-				return runCompleteMsg{
-					result:   result,
-					isWarmup: msg.isWarmup,
-					output:   []string{},
-				}
-
-			case line, ok := <-msg.outputLines:
-				if ok {
-					if line == "Match found!" && msg.phraseMatchResult != nil {
-						if msg.cancel != nil {
-							close(msg.cancel)
-							if msg.cmd.Process != nil {
-								msg.cmd.Process.Kill()
-							}
-						}
-
-						updatedMsg := msg
-						updatedMsg.matchFoundReceived = true
-						return newOutputLineMsg{
-							line:       line,
-							streamNext: updatedMsg,
-						}
-					}
+// outputKind says what a line in the output pane is, and so how it is styled.
+type outputKind int
+
+const (
+	outputCommand outputKind = iota
+	outputSeparator
+	outputCommandHeader
+	outputHook
+	outputHookFailure
+	outputMatch
+	outputFailure
+)
 
-					return newOutputLineMsg{
-						line:       line,
-						streamNext: msg,
-					}
-				} else {
-					if msg.commandCompleted && msg.completionResult != nil {
-						return runCompleteMsg{
-							result:   *msg.completionResult,
-							isWarmup: msg.isWarmup,
-							output:   []string{},
-						}
-					}
+type outputLine struct {
+	kind outputKind
+	text string
+}
 
-					if msg.phrase != "" {
-						result := benchmark.Result{
-							Duration: 0,
-							Found:    false,
-						}
+func formatOutputLine(line benchmark.Line) outputLine {
+	text := sanitizeOutput(line.Text)
+	switch line.Kind {
+	case benchmark.LineStderr:
+		return outputLine{kind: outputCommand, text: "stderr: " + text}
+	case benchmark.LineMatch:
+		return outputLine{kind: outputMatch, text: text}
+	case benchmark.LineFailure:
+		return outputLine{kind: outputFailure, text: text}
+	default:
+		return outputLine{kind: outputCommand, text: text}
+	}
+}
 
-						return runCompleteMsg{
-							result:   result,
-							isWarmup: msg.isWarmup,
-							output:   []string{},
-						}
-					}
+// sanitizeOutput keeps the colours in a line of command output, as written
+// by programs that think they are talking to a terminal, and drops the other
+// escape sequences and control characters, which would move the cursor or
+// otherwise break the layout. The styles are reset at the end of the line so
+// that they do not leak into the rest of the view.
+func sanitizeOutput(text string) string {
+	if !strings.ContainsFunc(text, unicode.IsControl) {
+		return text
+	}
 
-					return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-						return msg
-					})()
-				}
+	var s strings.Builder
+	var state byte
+	styled := false
+	for len(text) > 0 {
+		sequence, width, n, newState := ansi.DecodeSequence(text, state, nil)
+		state = newState
+		text = text[n:]
+
+		switch {
+		case width > 0:
+			s.WriteString(sequence)
+		case sequence == "\t":
+			s.WriteString("    ")
+		case ansi.HasCsiPrefix(sequence) && strings.HasSuffix(sequence, "m"):
+			s.WriteString(sequence)
+			styled = true
+		}
+	}
+	if styled {
+		s.WriteString(ansi.ResetStyle)
+	}
+	return s.String()
+}
 
-			default:
-				if msg.phraseMatchResult != nil && msg.matchFoundReceived {
-					return runCompleteMsg{
-						result:   *msg.phraseMatchResult,
-						isWarmup: msg.isWarmup,
-						output:   []string{},
-					}
-				}
+func (m Model) handleStreamNext(msg streamNextMsg) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-msg.lines
+		if !ok {
+			return <-msg.done
+		}
 
-				return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-					return msg
-				})()
-			}
-		} else {
+		lines := []outputLine{formatOutputLine(line)}
+		for len(lines) < OutputChannelBuffer {
 			select {
-			case duration := <-msg.done:
-				if msg.cancel != nil {
-					result := m.createAdjustedResult(duration, true)
-
-					updatedMsg := msg
-					updatedMsg.phraseMatchResult = &result
-
-					return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-						return updatedMsg
-					})()
-				} else {
-					result := m.createAdjustedResult(duration, true)
-
-					updatedMsg := msg
-					updatedMsg.commandCompleted = true
-					updatedMsg.completionResult = &result
-
-					return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-						return updatedMsg
-					})()
+			case line, ok := <-msg.lines:
+				if !ok {
+					return newOutputLineMsg{lines: lines, streamNext: msg}
 				}
-
-			case line, ok := <-msg.outputLines:
-				if ok {
-					if line == "Match found!" && msg.phraseMatchResult != nil {
-						if msg.cancel != nil {
-							close(msg.cancel)
-							if msg.cmd.Process != nil {
-								msg.cmd.Process.Kill()
-							}
-						}
-
-						updatedMsg := msg
-						updatedMsg.matchFoundReceived = true
-						return newOutputLineMsg{
-							line:       line,
-							streamNext: updatedMsg,
-						}
-					}
-
-					return newOutputLineMsg{
-						line:       line,
-						streamNext: msg,
-					}
-				} else {
-					if msg.commandCompleted && msg.completionResult != nil {
-						return runCompleteMsg{
-							result:   *msg.completionResult,
-							isWarmup: msg.isWarmup,
-							output:   []string{},
-						}
-					}
-
-					if msg.phrase != "" {
-						result := benchmark.Result{
-							Duration: 0,
-							Found:    false,
-						}
-
-						return runCompleteMsg{
-							result:   result,
-							isWarmup: msg.isWarmup,
-							output:   []string{},
-						}
-					}
-
-					return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-						return msg
-					})()
-				}
-
+				lines = append(lines, formatOutputLine(line))
 			default:
-				if msg.phraseMatchResult != nil && msg.matchFoundReceived {
-					return runCompleteMsg{
-						result:   *msg.phraseMatchResult,
-						isWarmup: msg.isWarmup,
-						output:   []string{},
-					}
-				}
-
-				return tea.Tick(StreamTickInterval, func(t time.Time) tea.Msg {
-					return msg
-				})()
+				return newOutputLineMsg{lines: lines, streamNext: msg}
 			}
 		}
+
+		return newOutputLineMsg{lines: lines, streamNext: msg}
 	}
 }
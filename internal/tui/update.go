@@ -2,8 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"chrono/internal/benchmark"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -37,6 +40,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			m.scrollOffset = m.getMaxScrollOffset()
 			return m, nil
+		case "tab":
+			m.selected = (m.selected + 1) % len(m.commands)
+			return m, nil
+		case "shift+tab":
+			m.selected = (m.selected + len(m.commands) - 1) % len(m.commands)
+			return m, nil
 		}
 
 	case tea.MouseMsg:
@@ -68,109 +77,59 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case calibrationCompleteMsg:
 		m.shellOverhead = msg.overhead
-		if m.config.Warmups > 0 {
-			m.state = StateWarmup
-			return m, m.startWarmup()
-		}
-		m.state = StateBenchmarking
-		return m, m.startBenchmark()
+		return m, m.runHook(benchmark.HookSetup, false)
+
+	case hookCompleteMsg:
+		return m.handleHookComplete(msg)
 
 	case runStartMsg:
 		m.currentRunStartTime = time.Now()
 		m.isRunning = true
 		m.elapsedTime = 0
 
-		if !msg.isWarmup {
-			runNumber := m.benchmarkProgress + 1
-
-			maxScroll := m.getMaxScrollOffset()
-			shouldAutoScroll := m.scrollOffset >= maxScroll-1
-
-			m.commandOutput = append(m.commandOutput, "")
-			separator := fmt.Sprintf("--- Benchmark Run %d ---", runNumber)
-			m.commandOutput = append(m.commandOutput, separator)
-			m.commandOutput = append(m.commandOutput, "")
-
-			if shouldAutoScroll {
-				m.scrollOffset = m.getMaxScrollOffset()
-			}
+		if msg.isWarmup {
+			m.state = StateWarmup
+		} else {
+			m.state = StateBenchmarking
 		}
 
 		return m, nil
-	case startStreamingMsg:
-		return m, m.startStreaming(msg)
-
 	case streamNextMsg:
 		return m, m.handleStreamNext(msg)
 
 	case newOutputLineMsg:
 		shouldAutoScroll := m.autoScrollToBottom()
-		m.commandOutput = append(m.commandOutput, msg.line)
+		m.commandOutput = append(m.commandOutput, msg.lines...)
 		if shouldAutoScroll {
 			m.scrollOffset = m.getMaxScrollOffset()
 		}
 
-		if msg.streamNext.matchFoundReceived && msg.streamNext.phraseMatchResult != nil {
-			return m, tea.Cmd(func() tea.Msg {
-				return runCompleteMsg{
-					result:   *msg.streamNext.phraseMatchResult,
-					isWarmup: msg.streamNext.isWarmup,
-					output:   []string{},
-				}
-			})
-		}
-
-		if msg.streamNext.commandCompleted && msg.streamNext.completionResult != nil && msg.streamNext.phrase == "" {
-			return m, tea.Cmd(func() tea.Msg {
-				select {
-				case line, ok := <-msg.streamNext.outputLines:
-					if ok {
-						return newOutputLineMsg{
-							line:       line,
-							streamNext: msg.streamNext,
-						}
-					} else {
-						return runCompleteMsg{
-							result:   *msg.streamNext.completionResult,
-							isWarmup: msg.streamNext.isWarmup,
-							output:   []string{},
-						}
-					}
-				default:
-					return msg.streamNext
-				}
-			})
-		}
-
 		return m, m.handleStreamNext(msg.streamNext)
 
 	case runCompleteMsg:
 		m.isRunning = false
+		command := &m.commands[m.current]
 
 		if msg.isWarmup {
-			m.warmupResults = append(m.warmupResults, msg.result)
+			command.warmupResults = append(command.warmupResults, msg.result)
 			m.warmupProgress++
-			m.currentRun++
-
-			if m.warmupProgress < m.config.Warmups {
-				return m, m.startWarmup()
-			}
-
-			m.state = StateBenchmarking
-			return m, m.startBenchmark()
 		} else {
-			m.benchmarkResults = append(m.benchmarkResults, msg.result)
+			command.benchmarkResults = append(command.benchmarkResults, msg.result)
 			m.benchmarkProgress++
-			m.currentRun++
+		}
+		m.currentRun++
 
-			if m.benchmarkProgress < m.config.Runs {
-				return m, m.startBenchmark()
+		if msg.err != nil {
+			command.runErr = msg.err
+			shouldAutoScroll := m.autoScrollToBottom()
+			m.commandOutput = append(m.commandOutput, outputLine{kind: outputFailure, text: fmt.Sprintf("Run failed: %v", msg.err)})
+			if shouldAutoScroll {
+				m.scrollOffset = m.getMaxScrollOffset()
 			}
-
-			m.state = StateCompleted
-			return m, nil
 		}
 
+		return m, m.runHook(benchmark.HookCleanup, msg.isWarmup)
+
 	case outputLineMsg:
 		shouldAutoScroll := m.autoScrollToBottom()
 		m.commandOutput = append(m.commandOutput, msg.line)
@@ -200,3 +159,112 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return m, nil
 }
+
+func (m Model) handleHookComplete(msg hookCompleteMsg) (tea.Model, tea.Cmd) {
+	if command := m.config.HookCommand(msg.hook); command != "" {
+		shouldAutoScroll := m.autoScrollToBottom()
+		m.commandOutput = append(m.commandOutput, outputLine{kind: outputHook, text: fmt.Sprintf("[%s] $ %s", msg.hook, command)})
+		for line := range strings.SplitSeq(strings.TrimRight(msg.output, "\n"), "\n") {
+			if line != "" {
+				m.commandOutput = append(m.commandOutput, outputLine{kind: outputHook, text: fmt.Sprintf("[%s] %s", msg.hook, line)})
+			}
+		}
+		if msg.err != nil {
+			m.commandOutput = append(m.commandOutput, outputLine{kind: outputHookFailure, text: fmt.Sprintf("[%s] Hook failed: %v", msg.hook, msg.err)})
+		}
+		if shouldAutoScroll {
+			m.scrollOffset = m.getMaxScrollOffset()
+		}
+	}
+
+	if msg.err != nil {
+		m.commands[m.current].hookErr = msg.err
+		if msg.hook == benchmark.HookSetup || msg.hook == benchmark.HookConclude {
+			return m.finishCommand()
+		}
+		return m, m.runHook(benchmark.HookConclude, false)
+	}
+
+	switch msg.hook {
+	case benchmark.HookSetup:
+		if m.config.Warmups > 0 {
+			return m.startRun(true)
+		}
+		if m.config.Runs > 0 {
+			return m.startRun(false)
+		}
+		return m, m.runHook(benchmark.HookConclude, false)
+
+	case benchmark.HookPrepare:
+		if msg.isWarmup {
+			return m, m.startWarmup()
+		}
+		return m, m.startBenchmark()
+
+	case benchmark.HookCleanup:
+		if m.commands[m.current].runErr != nil {
+			return m, m.runHook(benchmark.HookConclude, false)
+		}
+		if msg.isWarmup && m.warmupProgress < m.config.Warmups {
+			return m.startRun(true)
+		}
+		command := &m.commands[m.current]
+		command.stopReason = m.config.StopReason(command.benchmarkResults, time.Since(m.seriesStartTime))
+		if command.stopReason == benchmark.StopNone {
+			return m.startRun(false)
+		}
+		return m, m.runHook(benchmark.HookConclude, false)
+	}
+
+	return m.finishCommand()
+}
+
+func (m Model) startRun(isWarmup bool) (tea.Model, tea.Cmd) {
+	if !isWarmup {
+		runNumber := m.benchmarkProgress + 1
+		if runNumber == 1 {
+			m.seriesStartTime = time.Now()
+		}
+
+		maxScroll := m.getMaxScrollOffset()
+		shouldAutoScroll := m.scrollOffset >= maxScroll-1
+
+		m.commandOutput = append(m.commandOutput, outputLine{})
+		separator := fmt.Sprintf("--- Benchmark Run %d ---", runNumber)
+		m.commandOutput = append(m.commandOutput, outputLine{kind: outputSeparator, text: separator})
+		m.commandOutput = append(m.commandOutput, outputLine{})
+
+		if shouldAutoScroll {
+			m.scrollOffset = m.getMaxScrollOffset()
+		}
+	}
+
+	return m, m.runHook(benchmark.HookPrepare, isWarmup)
+}
+
+func (m Model) finishCommand() (tea.Model, tea.Cmd) {
+	if m.current+1 < len(m.commands) {
+		return m.startNextCommand()
+	}
+
+	m.state = StateCompleted
+	return m, nil
+}
+
+func (m Model) startNextCommand() (tea.Model, tea.Cmd) {
+	if m.selected == m.current {
+		m.selected++
+	}
+	m.current++
+	m.config = m.commands[m.current].config
+	m.warmupProgress = 0
+	m.benchmarkProgress = 0
+
+	shouldAutoScroll := m.autoScrollToBottom()
+	m.commandOutput = m.appendCommandHeader(m.commandOutput)
+	if shouldAutoScroll {
+		m.scrollOffset = m.getMaxScrollOffset()
+	}
+
+	return m, m.runHook(benchmark.HookSetup, false)
+}
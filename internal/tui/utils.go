@@ -5,14 +5,35 @@ import (
 	"time"
 
 	"chrono/internal/benchmark"
+	"chrono/internal/stats"
 
-	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.3fs", d.Seconds())
 }
 
+func formatBytes(bytes float64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%.0f B", bytes)
+	}
+	value, exponent := bytes/unit, 0
+	for value >= unit && exponent < 3 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exponent])
+}
+
+func overheadLabel(config benchmark.Config) string {
+	if config.Shell != "" {
+		return "Shell"
+	}
+	return "Exec"
+}
+
 func (m Model) getMaxScrollOffset() int {
 	contentHeight := m.height - 6
 	availableLines := (contentHeight - 1) - 2
@@ -28,37 +49,55 @@ func (m Model) autoScrollToBottom() bool {
 	return m.scrollOffset >= maxScroll
 }
 
+// truncateToWidth shortens s to at most width cells, keeping any escape
+// sequences in it intact.
 func truncateToWidth(s string, width int) string {
-	if lipgloss.Width(s) <= width {
-		return s
+	return ansi.Truncate(s, width, "")
+}
+
+func (m Model) formatCommandDisplay(index int) string {
+	config := m.commands[index].config
+
+	label := "Command"
+	if len(m.commands) > 1 {
+		label = fmt.Sprintf("Command %d/%d", index+1, len(m.commands))
 	}
 
-	runes := []rune(s)
-	for i := len(runes) - 1; i >= 0; i-- {
-		candidate := string(runes[:i])
-		if lipgloss.Width(candidate) <= width {
-			return candidate
-		}
+	cmd := fmt.Sprintf("%s: %s", label, config.Command[0])
+	if len(config.Command) > 1 {
+		cmd += fmt.Sprintf(" %v", config.Command[1:])
+	}
+	if config.HasPhrase() {
+		cmd += fmt.Sprintf(" (phrase matched: %s)", config.PhraseString())
+	}
+	if config.HasProbe() {
+		cmd += fmt.Sprintf(" (until ready: %s)", config.Probe)
+	}
+	if config.UntilQuiet > 0 {
+		cmd += fmt.Sprintf(" (until quiet for %s)", config.UntilQuiet)
 	}
-	return ""
+	if config.ParameterName != "" {
+		cmd += fmt.Sprintf("\nParameter: %s = %s", config.ParameterName, config.ParameterValue)
+	}
+	return cmd
 }
 
-func (m Model) formatCommandDisplay() string {
-	cmd := fmt.Sprintf("Command: %s", m.config.Command[0])
-	if len(m.config.Command) > 1 {
-		cmd += fmt.Sprintf(" %v", m.config.Command[1:])
+func (m Model) appendCommandHeader(lines []outputLine) []outputLine {
+	if len(m.commands) < 2 {
+		return lines
 	}
-	if m.config.Phrase != "" {
-		cmd += fmt.Sprintf(" (phrase matched: \"%s\")", m.config.Phrase)
+	if len(lines) > 0 {
+		lines = append(lines, outputLine{})
 	}
-	return cmd
+	header := fmt.Sprintf("=== Command %d/%d: %s ===", m.current+1, len(m.commands), m.config.CommandString())
+	return append(lines, outputLine{kind: outputCommandHeader, text: header})
 }
 
-func (m Model) filterValidResults() ([]time.Duration, int) {
-	validResults := make([]time.Duration, 0, len(m.benchmarkResults))
+func filterValidResults(results []benchmark.Result) ([]time.Duration, int) {
+	validResults := make([]time.Duration, 0, len(results))
 	failedCount := 0
 
-	for _, result := range m.benchmarkResults {
+	for _, result := range results {
 		if result.Found {
 			validResults = append(validResults, result.Duration)
 		} else {
@@ -69,19 +108,41 @@ func (m Model) filterValidResults() ([]time.Duration, int) {
 	return validResults, failedCount
 }
 
-func (m Model) createAdjustedResult(duration time.Duration, found bool) benchmark.Result {
-	adjustedDuration := max(duration-m.shellOverhead, 0)
-	return benchmark.Result{
-		Duration: adjustedDuration,
-		Found:    found,
+func formatResult(result benchmark.Result) string {
+	if !result.Found {
+		return result.Cause()
+	}
+	if result.Status != benchmark.StatusSuccess {
+		return fmt.Sprintf("%s (%s)", formatDuration(result.Duration), result.Cause())
 	}
+	return formatDuration(result.Duration)
 }
 
-func (m Model) createTimeoutResult() benchmark.Result {
-	return benchmark.Result{
-		Duration: 0,
-		Found:    false,
+func (m Model) commandCompleted(index int) bool {
+	return index < m.current || m.state == StateCompleted
+}
+
+func (m Model) rankCommands() ([]int, []stats.Statistics) {
+	indices := make([]int, 0, len(m.commands))
+	statistics := make([]stats.Statistics, 0, len(m.commands))
+
+	for i, command := range m.commands {
+		validResults, _ := filterValidResults(command.benchmarkResults)
+		if len(validResults) == 0 {
+			continue
+		}
+		indices = append(indices, i)
+		statistics = append(statistics, stats.CalculateStatistics(validResults))
+	}
+
+	ranked := make([]int, 0, len(indices))
+	rankedStatistics := make([]stats.Statistics, 0, len(indices))
+	for _, i := range stats.Rank(statistics) {
+		ranked = append(ranked, indices[i])
+		rankedStatistics = append(rankedStatistics, statistics[i])
 	}
+
+	return ranked, rankedStatistics
 }
 
 func (m Model) calculateBoundedLeftWidth() int {
@@ -97,3 +158,13 @@ func (m Model) calculateBoundedLeftWidth() int {
 
 	return leftContentWidth
 }
+
+// precisionStatus describes the precision reached so far by a command with
+// --runs auto, for the status line.
+func precisionStatus(command commandResults) string {
+	rse := "-"
+	if value, ok := benchmark.Precision(command.benchmarkResults); ok {
+		rse = benchmark.FormatRSE(value)
+	}
+	return fmt.Sprintf(" · RSE %s (target %s)", rse, benchmark.FormatRSE(command.config.TargetRSE))
+}
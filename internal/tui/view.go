@@ -103,9 +103,12 @@ func (m Model) renderShortcuts() string {
 		return m.clipboardFeedback
 	}
 
+	shortcuts := "↑/↓ j/k: scroll • Esc: top/bottom"
+	if len(m.commands) > 1 {
+		shortcuts += " • Tab: switch command"
+	}
 	if m.state == StateCompleted {
-		return "↑/↓ j/k: scroll • Esc: top/bottom • y: copy results • q/Ctrl+C: quit"
-	} else {
-		return "↑/↓ j/k: scroll • Esc: top/bottom • q/Ctrl+C: quit"
+		shortcuts += " • y: copy results"
 	}
+	return shortcuts + " • q/Ctrl+C: quit"
 }